@@ -33,6 +33,25 @@ import (
 	_ "github.com/quentin-m/vautour/src/modules/elasticsearch"
 	_ "github.com/quentin-m/vautour/src/modules/yara"
 	_ "github.com/quentin-m/vautour/src/modules/mailer"
+	_ "github.com/quentin-m/vautour/src/modules/stackexchange"
+	_ "github.com/quentin-m/vautour/src/modules/httpjson"
+	_ "github.com/quentin-m/vautour/src/modules/htmlscrape"
+	_ "github.com/quentin-m/vautour/src/modules/webhook"
+	_ "github.com/quentin-m/vautour/src/modules/ct"
+	_ "github.com/quentin-m/vautour/src/modules/git"
+	_ "github.com/quentin-m/vautour/src/modules/secrets"
+	_ "github.com/quentin-m/vautour/src/modules/entropy"
+	_ "github.com/quentin-m/vautour/src/modules/ioc"
+	_ "github.com/quentin-m/vautour/src/modules/decode"
+	_ "github.com/quentin-m/vautour/src/modules/archive"
+	_ "github.com/quentin-m/vautour/src/modules/classify"
+	_ "github.com/quentin-m/vautour/src/modules/combolist"
+	_ "github.com/quentin-m/vautour/src/modules/watchlist"
+	_ "github.com/quentin-m/vautour/src/modules/fuzzyhash"
+	_ "github.com/quentin-m/vautour/src/modules/validate"
+	_ "github.com/quentin-m/vautour/src/modules/cryptocurrency"
+	_ "github.com/quentin-m/vautour/src/modules/sqldump"
+	_ "github.com/quentin-m/vautour/src/modules/configcreds"
 )
 
 func main() {
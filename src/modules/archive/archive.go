@@ -0,0 +1,132 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package archive
+
+import (
+	"encoding/json"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/unpack"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+)
+
+// archive fans the files held in archives (zip, tar, gzip, bzip2, zlib) and
+// multi-part messages out as child documents, each processed on its own.
+type archive struct {
+	// MaxDepth is the maximum depth of the child documents, i.e. of nested archives.
+	MaxDepth int
+	// MaxSize is the maximum number of bytes extracted out of a single document.
+	MaxSize int64
+	// MaxFiles is the maximum number of child documents per document.
+	MaxFiles int
+}
+
+// summary is the ProcessedData of an extracted document.
+type summary struct {
+	Format string
+	Files  []file
+}
+
+type file struct {
+	Name string
+	ID   string
+	Size int
+}
+
+func init() {
+	modules.Register("archive", &archive{})
+}
+
+func (a *archive) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	a.MaxDepth = 3
+	a.MaxSize = 50 * 1024 * 1024
+	a.MaxFiles = 100
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, a); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *archive) Process(d *vautour.Document) error {
+	if d.Depth >= a.MaxDepth || unpack.Detect(d.Content) == "" {
+		return nil
+	}
+
+	b := unpack.NewBudget(a.MaxSize)
+	format, files, err := unpack.Unpack(d.Content, b)
+	if err != nil {
+		log.WithField("role", "processor").WithField("module", "archive").WithField("item_id", d.ID).WithError(err).Debug("failed to fully extract document")
+	}
+
+	// Unwrap compressed archives (e.g. .tar.gz) right away, rather than
+	// yielding an intermediate child.
+	if unpack.IsStream(format) && len(files) == 1 && unpack.Detect(files[0].Content) != "" {
+		if inner, innerFiles, err := unpack.Unpack(files[0].Content, b); len(innerFiles) > 0 {
+			format, files = format+"+"+inner, innerFiles
+			if err != nil {
+				log.WithField("role", "processor").WithField("module", "archive").WithField("item_id", d.ID).WithError(err).Debug("failed to fully extract document")
+			}
+		}
+	}
+
+	s := summary{Format: format}
+	for _, f := range files {
+		if a.MaxFiles > 0 && len(s.Files) >= a.MaxFiles {
+			break
+		}
+		if len(f.Content) == 0 {
+			continue
+		}
+
+		name := f.Name
+		if name == "" {
+			name = format
+		}
+		c := &vautour.Document{
+			ID:        d.ID + "/" + name,
+			Title:     d.Title + " > " + name,
+			User:      d.User,
+			URL:       d.URL,
+			Content:   f.Content,
+			Size:      len(f.Content),
+			CreatedAt: d.CreatedAt,
+			ExpireAt:  d.ExpireAt,
+		}
+		c.SetMetadata("filename", name)
+		d.AddChild(c)
+		s.Files = append(s.Files, file{Name: name, ID: c.ID, Size: c.Size})
+	}
+	if len(s.Files) == 0 {
+		return nil
+	}
+
+	j, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	d.Processed = append(d.Processed, vautour.ProcessedData{
+		Module:     "archive",
+		RawMessage: j,
+	})
+	log.WithField("role", "processor").WithField("module", "archive").WithField("item_id", d.ID).WithField("files", len(s.Files)).Debug("extracted child documents")
+
+	return nil
+}
@@ -0,0 +1,89 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package classify
+
+import (
+	"bytes"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// classify sets the Classification of documents, and is meant to run before
+// the other processors.
+type classify struct {
+	// SampleSize is the number of leading bytes inspected to detect the
+	// charset & language.
+	SampleSize int
+	// Ratio is the share of lines that must follow a line-based format (e.g.
+	// env, csv, combo lists) for it to be detected.
+	Ratio float64
+}
+
+func init() {
+	modules.Register("classify", &classify{})
+}
+
+func (c *classify) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	c.SampleSize = 64 * 1024
+	c.Ratio = 0.8
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, c); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *classify) Process(d *vautour.Document) error {
+	d.Class = c.classify(d.Content)
+	log.WithField("role", "processor").WithField("module", "classify").WithField("item_id", d.ID).WithField("file_type", d.Class.FileType).WithField("language", d.Class.Language).Debug("classified document")
+	return nil
+}
+
+func (c *classify) classify(content []byte) *vautour.Classification {
+	sample := content
+	if c.SampleSize > 0 && len(sample) > c.SampleSize {
+		sample = sample[:c.SampleSize]
+	}
+
+	cl := &vautour.Classification{
+		FileType: detectFileType(content),
+		MIME:     http.DetectContentType(sample),
+		Lines:    bytes.Count(content, []byte("\n")),
+	}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		cl.Lines++
+	}
+
+	// Magic bytes such as `MZ` may begin text too, trust them on binaries only.
+	cl.Binary = isBinary(sample)
+	if !cl.Binary {
+		if cl.FileType != "pgp" {
+			cl.FileType = fileTypeText
+		}
+		cl.Charset = charset(sample)
+		cl.Language = detectLanguage(content, sample, c.Ratio)
+	} else if cl.FileType == "" {
+		cl.FileType = "binary"
+	}
+
+	return cl
+}
@@ -0,0 +1,198 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package classify
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"unicode/utf8"
+)
+
+const fileTypeText = "text"
+
+// magics maps the magic bytes of common binary formats to their file type.
+// Formats sharing a prefix are listed from the most specific.
+var magics = []struct {
+	offset   int
+	magic    []byte
+	fileType string
+}{
+	{0, []byte("\x7fELF"), "elf"},
+	{0, []byte("MZ"), "pe"},
+	{0, []byte{0xfe, 0xed, 0xfa, 0xce}, "macho"},
+	{0, []byte{0xfe, 0xed, 0xfa, 0xcf}, "macho"},
+	{0, []byte{0xce, 0xfa, 0xed, 0xfe}, "macho"},
+	{0, []byte{0xcf, 0xfa, 0xed, 0xfe}, "macho"},
+	{0, []byte{0xca, 0xfe, 0xba, 0xbe}, "java-class"},
+	{0, []byte("dex\n"), "dex"},
+	{0, []byte("%PDF-"), "pdf"},
+	{0, []byte{0xd0, 0xcf, 0x11, 0xe0, 0xa1, 0xb1, 0x1a, 0xe1}, "ole"},
+	{0, []byte("PK\x03\x04"), "zip"},
+	{0, []byte{0x1f, 0x8b}, "gzip"},
+	{0, []byte("BZh"), "bzip2"},
+	{0, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, "xz"},
+	{0, []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}, "7z"},
+	{0, []byte("Rar!\x1a\x07"), "rar"},
+	{257, []byte("ustar"), "tar"},
+	{0, []byte("SQLite format 3\x00"), "sqlite"},
+	{0, []byte("\x89PNG\r\n\x1a\n"), "png"},
+	{0, []byte{0xff, 0xd8, 0xff}, "jpeg"},
+	{0, []byte("GIF8"), "gif"},
+	{0, []byte("\x00asm"), "wasm"},
+	{0, []byte("-----BEGIN PGP MESSAGE-----"), "pgp"},
+}
+
+// languages are tried in order on text contents. Markers are decisive when
+// found in the first bytes, line patterns when most non-empty lines match.
+var languages = []struct {
+	name    string
+	markers *regexp.Regexp
+	lines   *regexp.Regexp
+}{
+	{"pem", regexp.MustCompile(`-----BEGIN [A-Z0-9 ]+-----`), nil},
+	{"php", regexp.MustCompile(`^\s*<\?php`), nil},
+	{"xml", regexp.MustCompile(`^\s*<\?xml `), nil},
+	{"html", regexp.MustCompile(`(?i)^\s*(<!doctype html|<html)`), nil},
+	{"shell", regexp.MustCompile(`^#!\s*/(usr/)?bin/(env\s+)?(ba|z|k)?sh\b`), nil},
+	{"python", regexp.MustCompile(`^#!\s*/(usr/)?bin/(env\s+)?python`), nil},
+	{"sql", regexp.MustCompile(`(?im)^(-- MySQL dump|-- PostgreSQL database dump|-- Dumping data for table|CREATE TABLE |INSERT INTO |COPY \S+ .*FROM stdin;)`), nil},
+	{"combolist", nil, regexp.MustCompile(`^[^\s:;|@]+@[^\s:;|@]+\.[A-Za-z]{2,}[:;|]\S+$`)},
+	{"env", nil, regexp.MustCompile(`^(export\s+)?[A-Za-z_][A-Za-z0-9_]*=.*$|^#.*$`)},
+	{"ini", regexp.MustCompile(`(?m)^\[[^\]\n]+\]\s*$`), nil},
+	{"yaml", nil, regexp.MustCompile(`^(---|\s*#.*|\s*- .*|\s*[\w.\-"']+:(\s.*)?)$`)},
+	{"csv", nil, regexp.MustCompile(`^[^,\n]*(,[^,\n]*){2,}$`)},
+	{"go", regexp.MustCompile(`(?m)^package \w+\s*$[\s\S]*^func `), nil},
+	{"javascript", regexp.MustCompile(`(?m)(require\(['"][\w./@-]+['"]\)|^\s*(export|import) .* from ['"])`), nil},
+	{"python", regexp.MustCompile(`(?m)^(def \w+\(.*\):|from [\w.]+ import |import \w+$)`), nil},
+}
+
+// detectFileType returns the file type of the content from its magic bytes,
+// or an empty string if unknown.
+func detectFileType(content []byte) string {
+	for _, m := range magics {
+		if len(content) >= m.offset+len(m.magic) && bytes.Equal(content[m.offset:m.offset+len(m.magic)], m.magic) {
+			return m.fileType
+		}
+	}
+	return ""
+}
+
+// isBinary returns whether the sample looks binary: it contains NUL bytes
+// (unless UTF-16), or a large share of control characters.
+func isBinary(sample []byte) bool {
+	if len(sample) == 0 || utf16BOM(sample) != "" {
+		return false
+	}
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+
+	control := 0
+	for _, c := range sample {
+		if c < 0x20 && c != '\n' && c != '\r' && c != '\t' && c != '\f' && c != 0x1b {
+			control++
+		}
+	}
+	return control*10 > len(sample)
+}
+
+// charset returns a hint of the encoding of text contents.
+func charset(sample []byte) string {
+	if bom := utf16BOM(sample); bom != "" {
+		return bom
+	}
+	if bytes.HasPrefix(sample, []byte{0xef, 0xbb, 0xbf}) {
+		return "utf-8"
+	}
+
+	ascii := true
+	for _, c := range sample {
+		if c >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	switch {
+	case ascii:
+		return "ascii"
+	case utf8.Valid(trimIncompleteRune(sample)):
+		return "utf-8"
+	default:
+		return "latin1"
+	}
+}
+
+func utf16BOM(sample []byte) string {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xff, 0xfe}):
+		return "utf-16le"
+	case bytes.HasPrefix(sample, []byte{0xfe, 0xff}):
+		return "utf-16be"
+	}
+	return ""
+}
+
+// trimIncompleteRune drops a rune cut in half at the end of a sample.
+func trimIncompleteRune(b []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// detectLanguage returns the language or format of text contents, or an
+// empty string if none stands out.
+func detectLanguage(content, sample []byte, ratio float64) string {
+	if t := bytes.TrimSpace(content); len(t) > 0 && (t[0] == '{' || t[0] == '[') && json.Valid(t) {
+		return "json"
+	}
+
+	var lines [][]byte
+	for _, l := range bytes.Split(sample, []byte("\n")) {
+		if l = bytes.TrimRight(l, "\r"); len(bytes.TrimSpace(l)) > 0 {
+			lines = append(lines, l)
+		}
+	}
+	// The last line of the sample may be cut.
+	if len(sample) < len(content) && len(lines) > 1 {
+		lines = lines[:len(lines)-1]
+	}
+
+	for _, l := range languages {
+		if l.markers != nil && l.markers.Match(sample) {
+			return l.name
+		}
+		if l.lines != nil && len(lines) >= 2 {
+			n := 0
+			for _, line := range lines {
+				if l.lines.Match(line) {
+					n++
+				}
+			}
+			if float64(n) >= ratio*float64(len(lines)) {
+				return l.name
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,260 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package combolist
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	formatEmail = "email:pass"
+	formatUser  = "user:pass"
+)
+
+// configLanguages are the languages (set by classify) of configuration files.
+var configLanguages = map[string]bool{"env": true, "ini": true, "yaml": true}
+
+var (
+	emailEntry = regexp.MustCompile(`^([^\s:;|@]+)@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})\s*[:;|,\t]\s*(\S.*)$`)
+	// Unlike configuration lines (`key: value`), user entries have no space
+	// around the separator.
+	userEntry = regexp.MustCompile(`^([A-Za-z0-9._\-]{3,64})[:;|](\S+)$`)
+	// Values of configuration lines, which are not passwords.
+	configValue = regexp.MustCompile(`(?i)^([0-9]+|[a-z]+|true|false|yes|no|on|off|null|~)$`)
+
+	// Types of the password field, from the most specific.
+	passwordTypes = []struct {
+		name string
+		re   *regexp.Regexp
+	}{
+		{"bcrypt", regexp.MustCompile(`^\$2[abxy]?\$\d{2}\$[./A-Za-z0-9]{53}$`)},
+		{"argon2", regexp.MustCompile(`^\$argon2(i|d|id)\$`)},
+		{"crypt", regexp.MustCompile(`^\$(1|5|6|apr1)\$[^$]+\$[./A-Za-z0-9]+$`)},
+		{"md5", regexp.MustCompile(`^[A-Fa-f0-9]{32}$`)},
+		{"sha1", regexp.MustCompile(`^[A-Fa-f0-9]{40}$`)},
+		{"sha256", regexp.MustCompile(`^[A-Fa-f0-9]{64}$`)},
+		{"sha512", regexp.MustCompile(`^[A-Fa-f0-9]{128}$`)},
+	}
+)
+
+// combolist detects credential dumps (email:pass, user;pass, hashes) and
+// breaks them down by domain, with a focus on the watched domains.
+type combolist struct {
+	// Domains are the watched domains, subdomains included.
+	Domains []string
+	// MinEntries & Ratio are the minimum number & share of non-empty lines
+	// that must be credentials for a document to be considered a combo list.
+	MinEntries int
+	Ratio      float64
+	// Score is the score of any combo list, raised by WatchedScore for every
+	// credential of a watched domain, up to MaxScore.
+	Score        int
+	WatchedScore int
+	MaxScore     int
+	// TopDomains is the number of domains kept in the breakdown.
+	TopDomains int
+}
+
+// breakdown is the ProcessedData of a combo list. It never holds passwords.
+type breakdown struct {
+	Entries       int
+	Formats       map[string]int
+	PasswordTypes map[string]int
+	Domains       []domainCount `json:",omitempty"`
+	Watched       []domainCount `json:",omitempty"`
+	WatchedTotal  int
+
+	// passwords are the offsets of the passwords in the content.
+	passwords [][2]int
+}
+
+type domainCount struct {
+	Domain   string
+	Count    int
+	Accounts int
+}
+
+func init() {
+	modules.Register("combolist", &combolist{})
+}
+
+func (c *combolist) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	c.MinEntries = 10
+	c.Ratio = 0.5
+	c.Score = 5
+	c.WatchedScore = 10
+	c.MaxScore = 100
+	c.TopDomains = 20
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, c); err != nil {
+		return err
+	}
+	for i := range c.Domains {
+		c.Domains[i] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(c.Domains[i]), "."))
+	}
+
+	return nil
+}
+
+func (c *combolist) Process(d *vautour.Document) error {
+	// Configuration files are made of `key:value` lines too.
+	if d.Class != nil && configLanguages[d.Class.Language] {
+		return nil
+	}
+
+	b, ok := c.analyze(d.Content)
+	if !ok {
+		return nil
+	}
+
+	j, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	score := c.Score
+	if b.WatchedTotal > 0 {
+		d.AddTags("combolist-watched")
+		score += c.WatchedScore * b.WatchedTotal
+	}
+	if c.MaxScore > 0 && score > c.MaxScore {
+		score = c.MaxScore
+	}
+
+	d.Processed = append(d.Processed, vautour.ProcessedData{
+		Module:     "combolist",
+		Score:      score,
+		RawMessage: j,
+	})
+	d.AddTags("combolist")
+	for _, p := range b.passwords {
+		d.MarkSensitive(p[0], p[1])
+	}
+	log.WithField("role", "processor").WithField("module", "combolist").WithField("item_id", d.ID).WithField("entries", b.Entries).WithField("watched", b.WatchedTotal).Debug("found combo list")
+
+	return nil
+}
+
+// analyze parses the content as a combo list, and returns its breakdown if it
+// is one.
+func (c *combolist) analyze(content []byte) (*breakdown, bool) {
+	b := &breakdown{Formats: make(map[string]int), PasswordTypes: make(map[string]int)}
+	counts := make(map[string]int)
+	accounts := make(map[string]map[string]bool)
+
+	lines := 0
+	for offset := 0; offset < len(content); {
+		end := bytes.IndexByte(content[offset:], '\n')
+		if end < 0 {
+			end = len(content)
+		} else {
+			end += offset
+		}
+		raw := content[offset:end]
+		// Offset of the trimmed line in the content.
+		start := offset + len(raw) - len(bytes.TrimLeftFunc(raw, unicode.IsSpace))
+		offset = end + 1
+
+		line := string(bytes.TrimSpace(raw))
+		if line == "" {
+			continue
+		}
+		lines++
+
+		var password []int
+		if m := emailEntry.FindStringSubmatchIndex(line); m != nil {
+			domain := strings.ToLower(line[m[4]:m[5]])
+			counts[domain]++
+			if accounts[domain] == nil {
+				accounts[domain] = make(map[string]bool)
+			}
+			accounts[domain][strings.ToLower(line[m[2]:m[3]])] = true
+			b.Formats[formatEmail]++
+			password = m[6:8]
+		} else if m := userEntry.FindStringSubmatchIndex(line); m != nil && !isConfigValue(line[m[4]:m[5]]) {
+			b.Formats[formatUser]++
+			password = m[4:6]
+		} else {
+			continue
+		}
+		b.Entries++
+		b.PasswordTypes[passwordType(line[password[0]:password[1]])]++
+		b.passwords = append(b.passwords, [2]int{start + password[0], start + password[1]})
+	}
+
+	if b.Entries < c.MinEntries || float64(b.Entries) < c.Ratio*float64(lines) {
+		return nil, false
+	}
+
+	for domain, n := range counts {
+		dc := domainCount{Domain: domain, Count: n, Accounts: len(accounts[domain])}
+		b.Domains = append(b.Domains, dc)
+		if c.watched(domain) {
+			b.Watched = append(b.Watched, dc)
+			b.WatchedTotal += n
+		}
+	}
+	sortCounts(b.Domains)
+	sortCounts(b.Watched)
+	if c.TopDomains > 0 && len(b.Domains) > c.TopDomains {
+		b.Domains = b.Domains[:c.TopDomains]
+	}
+
+	return b, true
+}
+
+func (c *combolist) watched(domain string) bool {
+	for _, w := range c.Domains {
+		if domain == w || strings.HasSuffix(domain, "."+w) {
+			return true
+		}
+	}
+	return false
+}
+
+// isConfigValue returns whether the value of a user entry is rather a bare
+// number, boolean or word, as found in configuration files.
+func isConfigValue(v string) bool {
+	return configValue.MatchString(v) && passwordType(v) == "plain"
+}
+
+func passwordType(p string) string {
+	for _, t := range passwordTypes {
+		if t.re.MatchString(p) {
+			return t.name
+		}
+	}
+	return "plain"
+}
+
+func sortCounts(dcs []domainCount) {
+	sort.Slice(dcs, func(i, j int) bool {
+		if dcs[i].Count != dcs[j].Count {
+			return dcs[i].Count > dcs[j].Count
+		}
+		return dcs[i].Domain < dcs[j].Domain
+	})
+}
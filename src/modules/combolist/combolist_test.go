@@ -0,0 +1,102 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package combolist
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+)
+
+func newCombolist(t *testing.T, params map[string]interface{}) *combolist {
+	c := &combolist{}
+	if err := c.Configure(&modules.ModuleConfig{Params: params}); err != nil {
+		t.Fatalf("could not configure: %s", err)
+	}
+	return c
+}
+
+func TestProcessComboList(t *testing.T) {
+	c := newCombolist(t, map[string]interface{}{"domains": []string{"example.com"}})
+
+	var b strings.Builder
+	for i := 0; i < 6; i++ {
+		fmt.Fprintf(&b, "john%d@example.com:Summer2019!%d\n", i, i)
+		fmt.Fprintf(&b, "jane%d@mail.example.org;5f4dcc3b5aa765d61d8327deb882cf9%d\n", i, i)
+		fmt.Fprintf(&b, "user_%d:p4ssw0rd%d\n", i, i)
+	}
+	d := &vautour.Document{Content: []byte(b.String())}
+	if err := c.Process(d); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Processed) != 1 || !d.HasTag("combolist") || !d.HasTag("combolist-watched") {
+		t.Fatalf("expected a watched combo list, got %+v (tags %v)", d.Processed, d.Tags)
+	}
+
+	var bd breakdown
+	if err := json.Unmarshal(d.Processed[0].RawMessage, &bd); err != nil {
+		t.Fatal(err)
+	}
+	if bd.Entries != 18 || bd.Formats[formatEmail] != 12 || bd.Formats[formatUser] != 6 || bd.PasswordTypes["md5"] != 6 || bd.WatchedTotal != 6 {
+		t.Errorf("unexpected breakdown %+v", bd)
+	}
+	if d.Processed[0].Score != 65 {
+		t.Errorf("expected a score of 65, got %d", d.Processed[0].Score)
+	}
+	if len(d.Sensitive) != 18 || string(d.Content[d.Sensitive[0].Start:d.Sensitive[0].End]) != "Summer2019!0" {
+		t.Errorf("unexpected sensitive spans %v", d.Sensitive)
+	}
+}
+
+func TestProcessConfigFile(t *testing.T) {
+	config := `service: api
+version: 2
+port: 8080
+host: 0.0.0.0
+debug: true
+log_level: debug
+workers: 4
+timeout: 30s
+database:
+  host: db.internal
+  port: 5432
+  name: api
+  sslmode: disable
+metrics: false
+`
+	env := "PORT=8080\nDEBUG=true\nLOG_LEVEL=debug\nWORKERS=4\nTIMEOUT=30\nHOST=0.0.0.0\nNAME=api\nMODE=prod\nCACHE=on\nRETRIES=3\n"
+	keys := "port:8080\ndebug:true\nlevel:debug\nworkers:4\nmode:prod\ncache:on\nretries:3\nname:api\ntls:off\nuser:null\n"
+
+	c := newCombolist(t, nil)
+	for name, d := range map[string]*vautour.Document{
+		"yaml":              {Content: []byte(config)},
+		"env":               {Content: []byte(env)},
+		"bare values":       {Content: []byte(keys)},
+		"classified as ini": {Content: []byte(strings.Repeat("admin:Summer2019!\n", 10)), Class: &vautour.Classification{Language: "ini"}},
+	} {
+		if err := c.Process(d); err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Processed) != 0 || len(d.Tags) != 0 || len(d.Sensitive) != 0 {
+			t.Errorf("%s: unexpected combo list %+v", name, d)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package configcreds
+
+import (
+	"encoding/json"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/secret"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"sort"
+)
+
+// configcreds parses configuration files (.env, docker-compose, Kubernetes
+// secrets, AWS credentials, wp-config.php) into key/value pairs, and extracts
+// the credentials they hold, as well as those of connection URIs & strings,
+// with their type & target host.
+type configcreds struct {
+	// Score is the score of the documents holding credentials.
+	Score int
+	// MaxPairs & MaxCredentials are the maximum numbers of pairs & credentials
+	// kept per document.
+	MaxPairs       int
+	MaxCredentials int
+}
+
+// config is the ProcessedData of a configuration file. Its secrets are
+// redacted.
+type config struct {
+	Format      string        `json:",omitempty"`
+	Pairs       []configPair  `json:",omitempty"`
+	Credentials []*credential `json:",omitempty"`
+}
+
+type configPair struct {
+	Key     string
+	Value   string
+	Section string `json:",omitempty"`
+	Line    int
+}
+
+func init() {
+	modules.Register("configcreds", &configcreds{})
+}
+
+func (c *configcreds) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	c.Score = 30
+	c.MaxPairs = 100
+	c.MaxCredentials = 20
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, c); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *configcreds) Process(d *vautour.Document) error {
+	cfg := config{Format: detectFormat(d.Content)}
+	var pairs []pair
+	if cfg.Format != "" {
+		pairs = parse(d.Content, cfg.Format)
+	}
+
+	creds := groupPairs(pairs, cfg.Format)
+	creds = append(creds, findURIs(d.Content)...)
+	creds = append(creds, findConnectionStrings(d.Content)...)
+	if cfg.Format == "" && len(creds) == 0 {
+		return nil
+	}
+
+	// The values of the pairs holding (part of) a credential are redacted, as
+	// well as every value of Kubernetes secrets (e.g. TLS keys, registry auths).
+	var secrets [][2]int
+	for _, cred := range creds {
+		secrets = append(secrets, cred.secrets...)
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i][0] < secrets[j][0] })
+	for _, p := range pairs {
+		isSecret := false
+		if role, _ := keyRole(p.key); role == roleSecret {
+			isSecret = true
+		} else if cfg.Format == formatKubernetes && isSecretData(p) {
+			isSecret = true
+			d.MarkSensitive(p.start, p.end)
+		}
+		if c.MaxPairs > 0 && len(cfg.Pairs) >= c.MaxPairs {
+			continue
+		}
+
+		value := p.value
+		if isSecret {
+			value = secret.Redact(value)
+		} else {
+			value = redactValue(d.Content, p, secrets)
+		}
+		cfg.Pairs = append(cfg.Pairs, configPair{Key: p.key, Value: value, Section: p.section, Line: p.line})
+	}
+
+	var descriptions []string
+	var spans []vautour.Span
+	seen := make(map[string]bool)
+	for _, cred := range creds {
+		for _, s := range cred.secrets {
+			d.MarkSensitive(s[0], s[1])
+			spans = append(spans, vautour.Span{Start: s[0], End: s[1]})
+		}
+		if c.MaxCredentials > 0 && len(cfg.Credentials) >= c.MaxCredentials {
+			continue
+		}
+		cred.Description = cred.describe()
+		cred.Secret = secret.Redact(cred.Secret)
+		cfg.Credentials = append(cfg.Credentials, cred)
+		if !seen[cred.Description] {
+			seen[cred.Description] = true
+			descriptions = append(descriptions, cred.Description)
+		}
+	}
+
+	j, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	pd := vautour.ProcessedData{
+		Module:     "configcreds",
+		Spans:      spans,
+		RawMessage: j,
+	}
+	if len(creds) > 0 {
+		pd.Score = c.Score
+		d.AddTags("config-credentials")
+		d.SetMetadata("credentials", descriptions)
+	}
+	d.Processed = append(d.Processed, pd)
+	log.WithField("role", "processor").WithField("module", "configcreds").WithField("item_id", d.ID).WithField("format", cfg.Format).WithField("credentials", len(creds)).Debug("parsed configuration")
+
+	return nil
+}
+
+// redactValue returns the value of the pair, with the secrets of the content
+// (sorted by offset) that overlap it redacted.
+func redactValue(b []byte, p pair, secrets [][2]int) string {
+	value := ""
+	offset := p.start
+	for _, s := range secrets {
+		if s[1] <= p.start || s[0] >= p.end {
+			continue
+		}
+		if p.value != string(b[p.start:p.end]) {
+			// The value was decoded, its secrets cannot be located in it.
+			return secret.Redact(p.value)
+		}
+		start, end := s[0], s[1]
+		if start < offset {
+			start = offset
+		}
+		if end > p.end {
+			end = p.end
+		}
+		if start >= end {
+			continue
+		}
+		value += string(b[offset:start]) + secret.Redact(string(b[start:end]))
+		offset = end
+	}
+	if offset == p.start {
+		return p.value
+	}
+	return value + string(b[offset:p.end])
+}
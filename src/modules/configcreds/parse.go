@@ -0,0 +1,261 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package configcreds
+
+import (
+	"bytes"
+	"encoding/base64"
+	"regexp"
+	"strings"
+)
+
+const (
+	formatEnv              = "env"
+	formatAWS              = "aws-credentials"
+	formatCompose          = "docker-compose"
+	formatKubernetes       = "kubernetes-secret"
+	formatWordPress        = "wp-config"
+	formatURI              = "uri"
+	formatConnectionString = "connection-string"
+)
+
+var (
+	envLine     = regexp.MustCompile(`^\s*(?:export\s+)?([A-Za-z_][A-Za-z0-9_.\-]*)\s*=\s*(.*?)\s*$`)
+	strictEnv   = regexp.MustCompile(`^\s*(?:export\s+)?[A-Za-z_][A-Za-z0-9_]*=\S*`)
+	iniSection  = regexp.MustCompile(`^\s*\[([^\]]+)\]\s*$`)
+	yamlLine    = regexp.MustCompile(`^(\s*)(?:-\s+)?["']?([A-Za-z0-9_.\-]+)["']?\s*:(?:\s+(.*?))?\s*$`)
+	yamlEnvItem = regexp.MustCompile(`^\s*-\s+["']?([A-Za-z_][A-Za-z0-9_.]*)=(.*?)\s*$`)
+	phpDefine   = regexp.MustCompile(`define\(\s*['"](\w+)['"]\s*,\s*(?:'([^']*)'|"([^"]*)")\s*\)`)
+	phpVariable = regexp.MustCompile(`\$(\w+)\s*=\s*(?:'([^']*)'|"([^"]*)")\s*;`)
+
+	awsCredentials = regexp.MustCompile(`(?im)^\s*aws_access_key_id\s*=`)
+	composeFile    = regexp.MustCompile(`(?m)^services:\s*$`)
+	composeService = regexp.MustCompile(`(?m)^\s+(?:image|build):`)
+	kubernetesKind = regexp.MustCompile(`(?m)^kind:\s*Secret\s*$`)
+	wordPress      = regexp.MustCompile(`define\(\s*['"]DB_PASSWORD['"]|\$table_prefix\s*=`)
+)
+
+// pair is a key/value pair of a configuration file.
+type pair struct {
+	key   string
+	value string
+	// section is the INI section or the YAML path of the pair.
+	section string
+	line    int
+	// start & end are the offsets of the raw value in the content.
+	start, end int
+}
+
+// line is a line of the content, with its offsets.
+type line struct {
+	number     int
+	start, end int
+}
+
+func lines(b []byte) []line {
+	var ls []line
+	for offset, n := 0, 1; offset < len(b); n++ {
+		end := bytes.IndexByte(b[offset:], '\n')
+		if end < 0 {
+			end = len(b)
+		} else {
+			end += offset
+		}
+		ls = append(ls, line{n, offset, end})
+		offset = end + 1
+	}
+	return ls
+}
+
+// detectFormat returns the configuration file format of b, if any.
+func detectFormat(b []byte) string {
+	switch {
+	case wordPress.Match(b):
+		return formatWordPress
+	case kubernetesKind.Match(b):
+		return formatKubernetes
+	case composeFile.Match(b) && composeService.Match(b):
+		return formatCompose
+	case awsCredentials.Match(b):
+		return formatAWS
+	case isEnv(b):
+		return formatEnv
+	}
+	return ""
+}
+
+// isEnv returns whether most of the non-empty, non-comment lines of b (at
+// least two) are environment variable definitions.
+func isEnv(b []byte) bool {
+	n, env := 0, 0
+	for _, l := range lines(b) {
+		t := bytes.TrimSpace(b[l.start:l.end])
+		if len(t) == 0 || t[0] == '#' {
+			continue
+		}
+		n++
+		if strictEnv.Match(t) {
+			env++
+		}
+	}
+	return env >= 2 && 2*env >= n
+}
+
+// parse returns the key/value pairs of b, in the given format.
+func parse(b []byte, format string) []pair {
+	switch format {
+	case formatEnv, formatAWS:
+		return parseINI(b)
+	case formatCompose, formatKubernetes:
+		ps := parseYAML(b)
+		if format == formatKubernetes {
+			decodeSecretData(ps)
+		}
+		return ps
+	case formatWordPress:
+		return parsePHP(b)
+	}
+	return nil
+}
+
+// parseINI parses KEY=value lines, in [sections] or not.
+func parseINI(b []byte) []pair {
+	var ps []pair
+	section := ""
+	for _, l := range lines(b) {
+		text := b[l.start:l.end]
+		if t := bytes.TrimSpace(text); len(t) == 0 || t[0] == '#' || t[0] == ';' {
+			continue
+		}
+		if m := iniSection.FindSubmatch(text); m != nil {
+			section = strings.TrimSpace(string(m[1]))
+			continue
+		}
+		if m := envLine.FindSubmatchIndex(text); m != nil {
+			ps = append(ps, newPair(b, string(text[m[2]:m[3]]), section, l, l.start+m[4], l.start+m[5]))
+		}
+	}
+	return ps
+}
+
+// parseYAML parses the key: value lines of b, given the path of their parents
+// as section, as well as the KEY=value items of lists (docker-compose
+// environments).
+func parseYAML(b []byte) []pair {
+	type parent struct {
+		indent int
+		key    string
+	}
+
+	var ps []pair
+	var stack []parent
+	path := func() string {
+		keys := make([]string, len(stack))
+		for i, p := range stack {
+			keys[i] = p.key
+		}
+		return strings.Join(keys, ".")
+	}
+
+	for _, l := range lines(b) {
+		text := b[l.start:l.end]
+		if t := bytes.TrimSpace(text); len(t) == 0 || t[0] == '#' {
+			continue
+		}
+		if m := yamlEnvItem.FindSubmatchIndex(text); m != nil {
+			ps = append(ps, newPair(b, string(text[m[2]:m[3]]), path(), l, l.start+m[4], l.start+m[5]))
+			continue
+		}
+		m := yamlLine.FindSubmatchIndex(text)
+		if m == nil {
+			continue
+		}
+
+		indent := m[3] - m[2]
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		key := string(text[m[4]:m[5]])
+		if m[6] < 0 || m[6] == m[7] {
+			stack = append(stack, parent{indent, key})
+			continue
+		}
+		ps = append(ps, newPair(b, key, path(), l, l.start+m[6], l.start+m[7]))
+	}
+	return ps
+}
+
+// isSecretData returns whether the pair is part of the data of a Kubernetes
+// secret.
+func isSecretData(p pair) bool {
+	for _, s := range []string{"data", "stringData"} {
+		if p.section == s || strings.HasSuffix(p.section, "."+s) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeSecretData decodes the base64 values of the data of Kubernetes
+// secrets.
+func decodeSecretData(ps []pair) {
+	for i, p := range ps {
+		if p.section != "data" && !strings.HasSuffix(p.section, ".data") {
+			continue
+		}
+		if v, err := base64.StdEncoding.DecodeString(p.value); err == nil {
+			ps[i].value = strings.TrimSpace(string(v))
+		}
+	}
+}
+
+// parsePHP parses the constants & variables of PHP configuration files.
+func parsePHP(b []byte) []pair {
+	var ps []pair
+	ls := lines(b)
+	for _, re := range []*regexp.Regexp{phpDefine, phpVariable} {
+		for _, m := range re.FindAllSubmatchIndex(b, -1) {
+			start, end := m[4], m[5]
+			if start < 0 {
+				start, end = m[6], m[7]
+			}
+			l := ls[bytes.Count(b[:m[0]], []byte("\n"))]
+			ps = append(ps, pair{key: string(b[m[2]:m[3]]), value: string(b[start:end]), line: l.number, start: start, end: end})
+		}
+	}
+	return ps
+}
+
+// newPair returns the pair of the raw value at b[start:end], without its
+// quotes, trailing comment & separator.
+func newPair(b []byte, key, section string, l line, start, end int) pair {
+	for end > start && (b[end-1] == ',' || b[end-1] == ';' || b[end-1] == ' ' || b[end-1] == '\t' || b[end-1] == '\r') {
+		end--
+	}
+	if end-start >= 2 && (b[start] == '"' || b[start] == '\'') {
+		if q := bytes.IndexByte(b[start+1:end], b[start]); q >= 0 {
+			return pair{key: key, value: string(b[start+1 : start+1+q]), section: section, line: l.number, start: start + 1, end: start + 1 + q}
+		}
+	}
+	if i := bytes.Index(b[start:end], []byte(" #")); i >= 0 {
+		end = start + i
+		for end > start && (b[end-1] == ' ' || b[end-1] == '\t') {
+			end--
+		}
+	}
+	return pair{key: key, value: string(b[start:end]), section: section, line: l.number, start: start, end: end}
+}
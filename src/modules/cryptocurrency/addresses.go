@@ -0,0 +1,361 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cryptocurrency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/quentin-m/vautour/src/pkg/keccak"
+	"regexp"
+	"strings"
+)
+
+const (
+	kindAddress    = "address"
+	kindPrivateKey = "private-key"
+	kindMnemonic   = "mnemonic"
+
+	formatUnchecksummed = "unchecksummed"
+)
+
+// extractor finds candidates with re, which parse validates & describes.
+type extractor struct {
+	re    *regexp.Regexp
+	parse func(string) (finding, bool)
+}
+
+var extractors = []extractor{
+	{regexp.MustCompile(`\b[1-9A-HJ-NP-Za-km-z]{25,52}\b`), parseBase58},
+	{regexp.MustCompile(`\b(?:bc|tb|ltc|tltc|BC|TB|LTC|TLTC)1[02-9ac-hj-np-zAC-HJ-NP-Z]{6,87}\b`), parseBech32},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]{40}\b`), parseEthereum},
+	{regexp.MustCompile(`\b[48][1-9A-HJ-NP-Za-km-z]{94}(?:[1-9A-HJ-NP-Za-km-z]{11})?\b`), parseMonero},
+}
+
+// ethereumContext matches the words introducing Ethereum addresses, which
+// the unchecksummed ones require on their line or the previous one: hashes &
+// keys are also written as 0x-prefixed hexadecimal strings.
+var ethereumContext = regexp.MustCompile(`(?i)\b(?:eth|ether|ethereum|erc-?20|erc-?721|wei|gwei|wallet|metamask|etherscan)\b`)
+
+// version describes a base58check version prefix.
+type version struct {
+	currency string
+	kind     string
+	format   string
+	network  string
+}
+
+var (
+	// addressVersions are the versions of the base58check addresses, which
+	// payload is a 20 bytes hash.
+	addressVersions = map[string]version{
+		"\x00":     {"bitcoin", kindAddress, "p2pkh", "mainnet"},
+		"\x05":     {"bitcoin", kindAddress, "p2sh", "mainnet"},
+		"\x6f":     {"bitcoin", kindAddress, "p2pkh", "testnet"},
+		"\xc4":     {"bitcoin", kindAddress, "p2sh", "testnet"},
+		"\x30":     {"litecoin", kindAddress, "p2pkh", "mainnet"},
+		"\x32":     {"litecoin", kindAddress, "p2sh", "mainnet"},
+		"\x1e":     {"dogecoin", kindAddress, "p2pkh", "mainnet"},
+		"\x16":     {"dogecoin", kindAddress, "p2sh", "mainnet"},
+		"\x4c":     {"dash", kindAddress, "p2pkh", "mainnet"},
+		"\x10":     {"dash", kindAddress, "p2sh", "mainnet"},
+		"\x41":     {"tron", kindAddress, "", "mainnet"},
+		"\x1c\xb8": {"zcash", kindAddress, "p2pkh", "mainnet"},
+		"\x1c\xbd": {"zcash", kindAddress, "p2sh", "mainnet"},
+	}
+
+	// wifVersions are the versions of the private keys in the Wallet Import
+	// Format, which payload is a 32 bytes key.
+	wifVersions = map[byte]version{
+		0x80: {"bitcoin", kindPrivateKey, "wif", "mainnet"},
+		0xef: {"bitcoin", kindPrivateKey, "wif", "testnet"},
+		0xb0: {"litecoin", kindPrivateKey, "wif", "mainnet"},
+		0x9e: {"dogecoin", kindPrivateKey, "wif", "mainnet"},
+	}
+
+	// bech32Networks are the human-readable parts of the segwit addresses.
+	bech32Networks = map[string]version{
+		"bc":   {"bitcoin", kindAddress, "", "mainnet"},
+		"tb":   {"bitcoin", kindAddress, "", "testnet"},
+		"ltc":  {"litecoin", kindAddress, "", "mainnet"},
+		"tltc": {"litecoin", kindAddress, "", "testnet"},
+	}
+
+	// moneroTags are the network tags of the Monero mainnet addresses.
+	moneroTags = map[byte]string{
+		18: "standard",
+		19: "integrated",
+		42: "subaddress",
+	}
+)
+
+func (v version) finding(value string) finding {
+	return finding{Currency: v.currency, Kind: v.kind, Format: v.format, Network: v.network, Value: value}
+}
+
+func parseBase58(s string) (finding, bool) {
+	if payload, ok := base58CheckDecode(s, bitcoinAlphabet); ok {
+		switch {
+		case len(payload) == 21:
+			v, ok := addressVersions[string(payload[:1])]
+			return v.finding(s), ok
+		case len(payload) == 22:
+			v, ok := addressVersions[string(payload[:2])]
+			return v.finding(s), ok
+		case len(payload) == 33, len(payload) == 34 && payload[33] == 0x01:
+			v, ok := wifVersions[payload[0]]
+			if len(payload) == 34 {
+				v.format = "wif-compressed"
+			}
+			return v.finding(s), ok
+		}
+		return finding{}, false
+	}
+
+	// Ripple uses its own alphabet, where the version 0 is encoded as 'r'.
+	if s[0] == 'r' {
+		if payload, ok := base58CheckDecode(s, rippleAlphabet); ok && len(payload) == 21 && payload[0] == 0 {
+			return finding{Currency: "ripple", Kind: kindAddress, Network: "mainnet", Value: s}, true
+		}
+	}
+	return finding{}, false
+}
+
+func parseBech32(s string) (finding, bool) {
+	hrp, data, constant, ok := bech32Decode(s)
+	if !ok || len(data) < 1 {
+		return finding{}, false
+	}
+	v, ok := bech32Networks[hrp]
+	if !ok {
+		return finding{}, false
+	}
+
+	// Witness version 0 uses bech32, & the following ones bech32m (BIP350).
+	witness := data[0]
+	program, ok := convertBits(data[1:])
+	if !ok || witness > 16 || len(program) < 2 || len(program) > 40 {
+		return finding{}, false
+	}
+	if witness == 0 {
+		if constant != bech32Constant {
+			return finding{}, false
+		}
+		switch len(program) {
+		case 20:
+			v.format = "p2wpkh"
+		case 32:
+			v.format = "p2wsh"
+		default:
+			return finding{}, false
+		}
+	} else {
+		if constant != bech32mConstant {
+			return finding{}, false
+		}
+		v.format = fmt.Sprintf("witness-v%d", witness)
+		if witness == 1 && len(program) == 32 {
+			v.format = "p2tr"
+		}
+	}
+	return v.finding(strings.ToLower(s)), true
+}
+
+// parseEthereum accepts the mixed-case addresses which EIP-55 checksum
+// matches, & the addresses in a single case, which carry no checksum: these
+// are reported as unchecksummed, and kept only in an Ethereum context.
+func parseEthereum(s string) (finding, bool) {
+	f := finding{Currency: "ethereum", Kind: kindAddress, Format: formatUnchecksummed, Value: s}
+	addr := s[2:]
+	lower := strings.ToLower(addr)
+	if addr == lower || addr == strings.ToUpper(addr) {
+		return f, true
+	}
+
+	h := keccak.Sum256([]byte(lower))
+	hh := hex.EncodeToString(h[:])
+	for i := 0; i < len(addr); i++ {
+		c := addr[i]
+		if c >= '0' && c <= '9' {
+			continue
+		}
+		upper := hh[i] >= '8'
+		if upper != (c >= 'A' && c <= 'F') {
+			return finding{}, false
+		}
+	}
+	f.Format = "eip55"
+	return f, true
+}
+
+// hasEthereumContext returns whether the line of b at offset, or the previous
+// one, mentions Ethereum.
+func hasEthereumContext(b []byte, offset int) bool {
+	start := bytes.LastIndexByte(b[:offset], '\n')
+	if start >= 0 {
+		start = bytes.LastIndexByte(b[:start], '\n')
+	}
+	end := bytes.IndexByte(b[offset:], '\n')
+	if end < 0 {
+		end = len(b)
+	} else {
+		end += offset
+	}
+	return ethereumContext.Match(b[start+1 : end])
+}
+
+// parseMonero verifies the Keccak-256 checksum of the Monero addresses:
+// network tag, public spend & view keys, payment ID for integrated ones.
+func parseMonero(s string) (finding, bool) {
+	b, ok := moneroDecode(s)
+	if !ok || (len(b) != 69 && len(b) != 77) {
+		return finding{}, false
+	}
+	format, ok := moneroTags[b[0]]
+	if !ok || (format == "integrated") != (len(b) == 77) {
+		return finding{}, false
+	}
+	h := keccak.Sum256(b[:len(b)-4])
+	if string(h[:4]) != string(b[len(b)-4:]) {
+		return finding{}, false
+	}
+	return finding{Currency: "monero", Kind: kindAddress, Format: format, Network: "mainnet", Value: s}, true
+}
+
+// mnemonicLengths are the word counts of BIP39 mnemonics, longest first.
+var mnemonicLengths = []int{24, 21, 18, 15, 12}
+
+var bip39Index = func() map[string]int {
+	m := make(map[string]int, len(bip39Words))
+	for i, w := range bip39Words {
+		m[w] = i
+	}
+	return m
+}()
+
+// findMnemonics returns the offsets of the BIP39 mnemonics of b: runs of
+// whitespace separated words of the wordlist, which checksum matches.
+func findMnemonics(b []byte) [][2]int {
+	type word struct {
+		start, end, index int
+	}
+
+	var spans [][2]int
+	var run []word
+	check := func() {
+		for i := 0; i+mnemonicLengths[len(mnemonicLengths)-1] <= len(run); {
+			found := false
+			for _, n := range mnemonicLengths {
+				if i+n > len(run) {
+					continue
+				}
+				indexes := make([]int, n)
+				for j := range indexes {
+					indexes[j] = run[i+j].index
+				}
+				if validMnemonic(indexes) {
+					spans = append(spans, [2]int{run[i].start, run[i+n-1].end})
+					i += n
+					found = true
+					break
+				}
+			}
+			if !found {
+				i++
+			}
+		}
+		run = run[:0]
+	}
+
+	// Mnemonics are either written on a single line, or one word per line,
+	// possibly numbered.
+	lineWords := 0
+	for i := 0; i < len(b); {
+		for i < len(b) && isSpace(b[i]) {
+			if b[i] == '\n' {
+				if lineWords > 1 {
+					check()
+				}
+				lineWords = 0
+			}
+			i++
+		}
+		start := i
+		for i < len(b) && !isSpace(b[i]) {
+			i++
+		}
+		if start == i {
+			break
+		}
+		if isNumbering(b[start:i]) {
+			continue
+		}
+		lineWords++
+		if index, ok := bip39Index[string(b[start:i])]; ok {
+			run = append(run, word{start, i, index})
+		} else {
+			check()
+		}
+	}
+	check()
+	return spans
+}
+
+// validMnemonic verifies the checksum of a mnemonic, given by the indexes of
+// its words: the first bits of the SHA-256 of the entropy.
+func validMnemonic(indexes []int) bool {
+	bits := make([]byte, 0, 11*len(indexes))
+	for _, index := range indexes {
+		for k := 10; k >= 0; k-- {
+			bits = append(bits, byte(index>>uint(k)&1))
+		}
+	}
+	cs := len(bits) / 33
+	entropy := make([]byte, (len(bits)-cs)/8)
+	for i := range entropy {
+		for k := 0; k < 8; k++ {
+			entropy[i] = entropy[i]<<1 | bits[8*i+k]
+		}
+	}
+
+	h := sha256.Sum256(entropy)
+	for k := 0; k < cs; k++ {
+		if bits[len(bits)-cs+k] != h[0]>>uint(7-k)&1 {
+			return false
+		}
+	}
+	return true
+}
+
+// isNumbering returns whether w numbers a list item, such as "1." or "12)".
+func isNumbering(w []byte) bool {
+	w = bytes.TrimRight(w, ".)")
+	if len(w) == 0 || len(w) > 2 {
+		return false
+	}
+	for _, c := range w {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,139 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cryptocurrency
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/secret"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+)
+
+// cryptocurrency extracts the cryptocurrency addresses (Bitcoin, Litecoin,
+// Dogecoin, Dash, Zcash, Tron, Ripple, Ethereum, Monero), private keys (WIF)
+// & BIP39 mnemonics of documents, keeping only those which checksum matches.
+type cryptocurrency struct {
+	// Score is the score of the documents holding private keys or mnemonics.
+	Score int
+	// MaxFindings is the maximum number of distinct findings per document.
+	MaxFindings int
+}
+
+// finding is the ProcessedData of an address, private key or mnemonic.
+type finding struct {
+	Currency string `json:",omitempty"`
+	Kind     string
+	Format   string `json:",omitempty"`
+	Network  string `json:",omitempty"`
+	Value    string
+	Line     int
+	Count    int
+}
+
+func init() {
+	modules.Register("cryptocurrency", &cryptocurrency{})
+}
+
+func (c *cryptocurrency) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	c.Score = 40
+	c.MaxFindings = 100
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, c); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *cryptocurrency) Process(d *vautour.Document) error {
+	var findings []*finding
+	byValue := make(map[string]*finding)
+	add := func(f finding, start, end int) {
+		if f.Kind != kindAddress {
+			d.MarkSensitive(start, end)
+		}
+		if e, ok := byValue[f.Value]; ok {
+			e.Count++
+			return
+		}
+		if c.MaxFindings > 0 && len(findings) >= c.MaxFindings {
+			return
+		}
+		f.Line = bytes.Count(d.Content[:start], []byte("\n")) + 1
+		f.Count = 1
+		byValue[f.Value] = &f
+		findings = append(findings, &f)
+	}
+
+	for _, e := range extractors {
+		for _, m := range e.re.FindAllIndex(d.Content, -1) {
+			f, ok := e.parse(string(d.Content[m[0]:m[1]]))
+			if !ok || (f.Format == formatUnchecksummed && !hasEthereumContext(d.Content, m[0])) {
+				continue
+			}
+			add(f, m[0], m[1])
+		}
+	}
+	for _, m := range findMnemonics(d.Content) {
+		var words []string
+		for _, w := range bytes.Fields(d.Content[m[0]:m[1]]) {
+			if !isNumbering(w) {
+				words = append(words, string(w))
+			}
+		}
+		add(finding{Kind: kindMnemonic, Format: "bip39", Value: strings.Join(words, " ")}, m[0], m[1])
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Line < findings[j].Line })
+
+	keys := 0
+	for _, f := range findings {
+		score := 0
+		if f.Kind != kindAddress {
+			keys++
+			score = c.Score
+			f.Value = secret.Redact(f.Value)
+		}
+
+		j, err := json.Marshal(f)
+		if err != nil {
+			log.WithField("role", "processor").WithField("module", "cryptocurrency").WithField("item_id", d.ID).Warn("failed to marshal finding")
+			continue
+		}
+		d.Processed = append(d.Processed, vautour.ProcessedData{
+			Module:     "cryptocurrency",
+			Score:      score,
+			RawMessage: j,
+		})
+	}
+
+	d.AddTags("cryptocurrency")
+	if keys > 0 {
+		d.AddTags("cryptocurrency-private-key")
+	}
+	log.WithField("role", "processor").WithField("module", "cryptocurrency").WithField("item_id", d.ID).WithField("findings", len(findings)).WithField("private_keys", keys).Debug("extracted cryptocurrency data")
+
+	return nil
+}
@@ -0,0 +1,170 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cryptocurrency
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+)
+
+func newCryptocurrency(t *testing.T, params map[string]interface{}) *cryptocurrency {
+	c := &cryptocurrency{}
+	if err := c.Configure(&modules.ModuleConfig{Params: params}); err != nil {
+		t.Fatalf("could not configure: %s", err)
+	}
+	return c
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		parse func(string) (finding, bool)
+		value string
+		want  string // currency/kind/format/network, or empty if invalid
+	}{
+		{"p2pkh", parseBase58, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", "bitcoin/address/p2pkh/mainnet"},
+		{"p2sh", parseBase58, "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", "bitcoin/address/p2sh/mainnet"},
+		{"testnet p2pkh", parseBase58, "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", "bitcoin/address/p2pkh/testnet"},
+		{"bad checksum", parseBase58, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb", ""},
+		{"wif", parseBase58, "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ", "bitcoin/private-key/wif/mainnet"},
+		{"wif compressed", parseBase58, "KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617", "bitcoin/private-key/wif-compressed/mainnet"},
+		{"wif bad checksum", parseBase58, "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTK", ""},
+		{"ripple", parseBase58, "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh", "ripple/address//mainnet"},
+
+		{"p2wpkh", parseBech32, "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", "bitcoin/address/p2wpkh/mainnet"},
+		{"p2wsh", parseBech32, "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", "bitcoin/address/p2wsh/testnet"},
+		{"p2tr", parseBech32, "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", "bitcoin/address/p2tr/mainnet"},
+		{"witness v16", parseBech32, "BC1SW50QGDZ25J", "bitcoin/address/witness-v16/mainnet"},
+		{"witness v2", parseBech32, "bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", "bitcoin/address/witness-v2/mainnet"},
+		{"mixed case", parseBech32, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3T4", ""},
+		{"v0 with bech32m", parseBech32, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh", ""},
+		{"v1 with bech32", parseBech32, "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", ""},
+		{"unknown hrp", parseBech32, "tc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq5zuyut", ""},
+
+		{"eip55", parseEthereum, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", "ethereum/address/eip55/"},
+		{"eip55", parseEthereum, "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", "ethereum/address/eip55/"},
+		{"eip55", parseEthereum, "0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB", "ethereum/address/eip55/"},
+		{"eip55", parseEthereum, "0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb", "ethereum/address/eip55/"},
+		{"eip55 bad checksum", parseEthereum, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", ""},
+		{"lower case", parseEthereum, "0xde709f2102306220921060314715629080e2fb77", "ethereum/address/unchecksummed/"},
+		{"upper case", parseEthereum, "0x52908400098527886E0F7030069857D2E4169EE7", "ethereum/address/unchecksummed/"},
+
+		{"monero", parseMonero, "44AFFq5kSiGBoZ4NMDwYtN18obc8AemS33DBLWs3H7otXft3XjrpDtQGv7SqSsaBYBb98uNbr2VBBEt7f2wfn3RVGQBEP3A", "monero/address/standard/mainnet"},
+		{"monero bad checksum", parseMonero, "44AFFq5kSiGBoZ4NMDwYtN18obc8AemS33DBLWs3H7otXft3XjrpDtQGv7SqSsaBYBb98uNbr2VBBEt7f2wfn3RVGQBEP3B", ""},
+	} {
+		f, ok := tc.parse(tc.value)
+		got := ""
+		if ok {
+			got = strings.Join([]string{f.Currency, f.Kind, f.Format, f.Network}, "/")
+		}
+		if got != tc.want {
+			t.Errorf("%s: expected %q for %s, got %q", tc.name, tc.want, tc.value, got)
+		}
+	}
+}
+
+func TestFindMnemonics(t *testing.T) {
+	abandon := strings.Repeat("abandon ", 11)
+	for _, tc := range []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"12 words", "seed: " + abandon + "about\n", []string{abandon + "about"}},
+		{"24 words", strings.Repeat("abandon ", 23) + "art", []string{strings.Repeat("abandon ", 23) + "art"}},
+		{"vector", "legal winner thank year wave sausage worth useful legal winner thank yellow", []string{"legal winner thank year wave sausage worth useful legal winner thank yellow"}},
+		{"numbered", "1. legal\n2. winner\n3. thank\n4. year\n5. wave\n6. sausage\n7. worth\n8. useful\n9. legal\n10. winner\n11. thank\n12. yellow\n", []string{"legal\n2. winner\n3. thank\n4. year\n5. wave\n6. sausage\n7. worth\n8. useful\n9. legal\n10. winner\n11. thank\n12. yellow"}},
+		{"bad checksum", abandon + "abandon", nil},
+		{"too short", "abandon abandon about", nil},
+		{"prose", "the wave of the year was a legal winner", nil},
+	} {
+		var got []string
+		for _, m := range findMnemonics([]byte(tc.content)) {
+			got = append(got, tc.content[m[0]:m[1]])
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestProcess(t *testing.T) {
+	c := newCryptocurrency(t, nil)
+
+	content := `donations: 1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa, 1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa
+ETH wallet: 0xde709f2102306220921060314715629080e2fb77
+checksummed: 0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed
+sha1: 0x52908400098527886e0f7030069857d2e4169ee7
+key: 5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ
+`
+	d := &vautour.Document{Content: []byte(content)}
+	if err := c.Process(d); err != nil {
+		t.Fatal(err)
+	}
+	if !d.HasTag("cryptocurrency") || !d.HasTag("cryptocurrency-private-key") {
+		t.Errorf("unexpected tags %v", d.Tags)
+	}
+
+	var got []string
+	for _, p := range d.Processed {
+		var f finding
+		if err := json.Unmarshal(p.RawMessage, &f); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, strings.Join([]string{f.Kind, f.Format, f.Value}, "/"))
+		if (f.Kind == kindPrivateKey) != (p.Score == 40) {
+			t.Errorf("unexpected score %d for %+v", p.Score, f)
+		}
+		if f.Format == "p2pkh" && (f.Line != 1 || f.Count != 2) {
+			t.Errorf("unexpected line & count for %+v", f)
+		}
+	}
+	want := []string{
+		"address/p2pkh/1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa",
+		"address/unchecksummed/0xde709f2102306220921060314715629080e2fb77",
+		"address/eip55/0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+	}
+	if len(got) != 4 || strings.Join(got[:3], ",") != strings.Join(want, ",") || !strings.HasPrefix(got[3], "private-key/wif/") || strings.Contains(got[3], "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ") {
+		t.Errorf("unexpected findings %q", got)
+	}
+
+	key := strings.Index(content, "5Hue")
+	if len(d.Sensitive) != 1 || d.Sensitive[0].Start != key || d.Sensitive[0].End != key+51 {
+		t.Errorf("unexpected sensitive spans %v", d.Sensitive)
+	}
+}
+
+func TestHasEthereumContext(t *testing.T) {
+	for _, tc := range []struct {
+		content string
+		want    bool
+	}{
+		{"send 1 ETH to 0x", true},
+		{"Ethereum:\n0x", true},
+		{"ether\n\n0x", false},
+		{"commit 0x", false},
+		{"0x", false},
+	} {
+		if got := hasEthereumContext([]byte(tc.content), strings.LastIndex(tc.content, "0x")); got != tc.want {
+			t.Errorf("%q: expected %t", tc.content, tc.want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cryptocurrency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"strings"
+)
+
+const (
+	bitcoinAlphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	rippleAlphabet  = "rpshnaf39wBUDNEGHJKLM4PQRST7VWXYZ2bcdeCg65jkm8oFqi1tuvAxyz"
+)
+
+// base58Decode decodes a base58 string with the given alphabet, leading zero
+// bytes being encoded by the first character of the alphabet.
+func base58Decode(s, alphabet string) ([]byte, bool) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(s); i++ {
+		v := strings.IndexByte(alphabet, s[i])
+		if v < 0 {
+			return nil, false
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(v)))
+	}
+
+	zeros := 0
+	for zeros < len(s) && s[zeros] == alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), n.Bytes()...), true
+}
+
+// base58CheckDecode decodes a base58check string, returning its payload
+// (version included) if its double SHA-256 checksum matches.
+func base58CheckDecode(s, alphabet string) ([]byte, bool) {
+	b, ok := base58Decode(s, alphabet)
+	if !ok || len(b) < 5 {
+		return nil, false
+	}
+	payload, checksum := b[:len(b)-4], b[len(b)-4:]
+	h := sha256.Sum256(payload)
+	h = sha256.Sum256(h[:])
+	if !bytes.Equal(h[:4], checksum) {
+		return nil, false
+	}
+	return payload, true
+}
+
+// moneroBlockSizes are the encoded sizes of the Monero base58 blocks, by
+// number of bytes.
+var moneroBlockSizes = []int{0, 2, 3, 5, 6, 7, 9, 10, 11}
+
+// moneroDecode decodes Monero's base58 variant, which encodes the data by
+// blocks of 8 bytes.
+func moneroDecode(s string) ([]byte, bool) {
+	var out []byte
+	for len(s) > 0 {
+		size := 11
+		if len(s) < size {
+			size = len(s)
+		}
+		n := -1
+		for i, es := range moneroBlockSizes {
+			if es == size {
+				n = i
+			}
+		}
+		if n < 0 {
+			return nil, false
+		}
+
+		v := new(big.Int)
+		radix := big.NewInt(58)
+		for i := 0; i < size; i++ {
+			d := strings.IndexByte(bitcoinAlphabet, s[i])
+			if d < 0 {
+				return nil, false
+			}
+			v.Mul(v, radix)
+			v.Add(v, big.NewInt(int64(d)))
+		}
+		if v.BitLen() > 8*n {
+			return nil, false
+		}
+		var block [8]byte
+		binary.BigEndian.PutUint64(block[:], v.Uint64())
+		out = append(out, block[8-n:]...)
+		s = s[size:]
+	}
+	return out, true
+}
+
+const (
+	bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+	bech32Constant  = 1
+	bech32mConstant = 0x2bc830a3
+)
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+// bech32Decode decodes a bech32 or bech32m string, returning its
+// human-readable part, its 5-bit data (checksum excluded) & the checksum
+// constant that matched.
+func bech32Decode(s string) (string, []byte, uint32, bool) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, 0, false
+	}
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) || len(s) > 90 {
+		return "", nil, 0, false
+	}
+	hrp := s[:sep]
+
+	var data []byte
+	for i := sep + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return "", nil, 0, false
+		}
+		data = append(data, byte(v))
+	}
+
+	values := make([]byte, 0, 2*len(hrp)+1+len(data))
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]&31)
+	}
+	values = append(values, data...)
+
+	c := bech32Polymod(values)
+	if c != bech32Constant && c != bech32mConstant {
+		return "", nil, 0, false
+	}
+	return hrp, data[:len(data)-6], c, true
+}
+
+// convertBits regroups 5-bit values into bytes, rejecting non-zero padding.
+func convertBits(data []byte) ([]byte, bool) {
+	var out []byte
+	acc, n := uint(0), uint(0)
+	for _, v := range data {
+		acc = acc<<5 | uint(v)
+		n += 5
+		if n >= 8 {
+			n -= 8
+			out = append(out, byte(acc>>n))
+		}
+	}
+	if n >= 5 || acc&(1<<n-1) != 0 {
+		return nil, false
+	}
+	return out, true
+}
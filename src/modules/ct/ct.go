@@ -0,0 +1,221 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ct
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/state"
+	"github.com/quentin-m/vautour/src/pkg/stopper"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ct tails Certificate Transparency logs (RFC 6962), and yields every logged
+// certificate as a document.
+type ct struct {
+	Interval time.Duration
+	Timeout  time.Duration
+	Logs     []string
+	// BatchSize is the number of entries requested at once, logs may return less.
+	BatchSize uint64
+	// MaxEntries is the maximum number of entries fetched per log & per interval.
+	MaxEntries uint64
+	// StateFile persists the position in each log, in memory only if empty.
+	StateFile string
+
+	state *state.State
+}
+
+func init() {
+	modules.Register("ct", &ct{})
+}
+
+func (c *ct) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	c.Interval = 30 * time.Second
+	c.Timeout = 15 * time.Second
+	c.BatchSize = 256
+	c.MaxEntries = 10000
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, c); err != nil {
+		return err
+	}
+	if len(c.Logs) == 0 {
+		return errors.New("no log configured")
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = 1
+	}
+	for i := range c.Logs {
+		c.Logs[i] = strings.TrimSuffix(c.Logs[i], "/")
+	}
+
+	s, err := state.Open(c.StateFile)
+	if err != nil {
+		return fmt.Errorf("could not load state file: %s", err)
+	}
+	c.state = s
+
+	return nil
+}
+
+func (c *ct) List(st *stopper.Stopper, ch chan *vautour.Document) error {
+	if c.Interval <= 0 {
+		<-st.Chan()
+		return nil
+	}
+
+	t := time.NewTicker(c.Interval)
+	defer t.Stop()
+	for {
+		// Wait for next loop.
+		select {
+		case <-st.Chan():
+			return nil
+		case <-t.C:
+		}
+
+		for _, l := range c.Logs {
+			if err := c.tail(st, l, ch); err != nil {
+				log.WithField("role", "lister").WithField("module", "ct").WithField("log", l).WithError(err).Warn("failed to list new certificates")
+			}
+		}
+	}
+}
+
+// tail yields the entries added to the log since the last call, and moves the
+// log's cursor forward.
+func (c *ct) tail(st *stopper.Stopper, l string, ch chan *vautour.Document) error {
+	var head sth
+	if err := c.get(l+"/ct/v1/get-sth", &head); err != nil {
+		return err
+	}
+
+	// Without cursor, start from the current head of the log.
+	var cursor uint64
+	if v, ok := c.state.Get(l); ok {
+		var err error
+		if cursor, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return fmt.Errorf("invalid cursor %q: %s", v, err)
+		}
+	} else {
+		cursor = head.TreeSize
+		if err := c.state.Set(l, strconv.FormatUint(cursor, 10)); err != nil {
+			return err
+		}
+	}
+
+	end := head.TreeSize
+	if end > cursor+c.MaxEntries {
+		end = cursor + c.MaxEntries
+	}
+	for cursor < end && st.IsRunning() {
+		last := cursor + c.BatchSize - 1
+		if last >= end {
+			last = end - 1
+		}
+
+		var es entries
+		if err := c.get(fmt.Sprintf("%s/ct/v1/get-entries?start=%d&end=%d", l, cursor, last), &es); err != nil {
+			return err
+		}
+		if len(es.Entries) == 0 {
+			return fmt.Errorf("no entries returned from %d", cursor)
+		}
+
+		for i, e := range es.Entries {
+			index := cursor + uint64(i)
+			d, err := c.document(l, index, &e)
+			if err != nil {
+				log.WithField("role", "lister").WithField("module", "ct").WithField("log", l).WithField("index", index).WithError(err).Debug("failed to parse entry")
+				continue
+			}
+			ch <- d
+		}
+
+		cursor += uint64(len(es.Entries))
+		if err := c.state.Set(l, strconv.FormatUint(cursor, 10)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *ct) document(l string, index uint64, e *entry) (*vautour.Document, error) {
+	cert, err := e.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &vautour.Document{
+		ID:        fmt.Sprintf("%s:%d", logName(l), index),
+		Title:     cert.names(),
+		User:      cert.Issuer.CommonName,
+		URL:       fmt.Sprintf("%s/ct/v1/get-entries?start=%d&end=%d", l, index, index),
+		Content:   cert.summary(),
+		CreatedAt: cert.Timestamp,
+		ExpireAt:  cert.NotAfter,
+	}
+	d.Size = len(d.Content)
+	d.SetMetadata("log", l)
+	d.SetMetadata("precertificate", cert.Precert)
+	d.SetMetadata("serial", cert.SerialNumber.String())
+	d.SetMetadata("issuer", cert.Issuer.String())
+	if len(cert.DNSNames) > 0 {
+		d.SetMetadata("dns_names", cert.DNSNames)
+	}
+
+	return d, nil
+}
+
+// Scrape does nothing, as certificates are fetched while listing.
+func (c *ct) Scrape(d *vautour.Document) error {
+	return nil
+}
+
+func (c *ct) get(u string, v interface{}) error {
+	client := &http.Client{Timeout: c.Timeout}
+	res, err := client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// logName shortens a log URL into a readable prefix for document IDs.
+func logName(l string) string {
+	u, err := url.Parse(l)
+	if err != nil {
+		return l
+	}
+	return u.Host + u.Path
+}
@@ -0,0 +1,199 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ct
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/stopper"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+)
+
+// stubLog is a CT log serving the entries it is given, at most two at once.
+type stubLog struct {
+	m       sync.Mutex
+	entries []entry
+}
+
+func (s *stubLog) add(e entry) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.entries = append(s.entries, e)
+}
+
+func (s *stubLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	switch r.URL.Path {
+	case "/ct/v1/get-sth":
+		json.NewEncoder(w).Encode(sth{TreeSize: uint64(len(s.entries)), Timestamp: 1546300800000})
+	case "/ct/v1/get-entries":
+		start, err1 := strconv.Atoi(r.URL.Query().Get("start"))
+		end, err2 := strconv.Atoi(r.URL.Query().Get("end"))
+		if err1 != nil || err2 != nil || start > end || end >= len(s.entries) {
+			http.Error(w, "invalid range", http.StatusBadRequest)
+			return
+		}
+		if end > start+1 {
+			end = start + 1
+		}
+		json.NewEncoder(w).Encode(entries{Entries: s.entries[start : end+1]})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newCertificate returns a self-signed certificate for the given names.
+func newCertificate(t *testing.T, cn string, names ...string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC),
+		DNSNames:     names,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func uint24Prefixed(b []byte) []byte {
+	return append([]byte{byte(len(b) >> 16), byte(len(b) >> 8), byte(len(b))}, b...)
+}
+
+// newEntry returns the log entry of a certificate, or of a pre-certificate
+// which TBSCertificate is left empty as it is not parsed.
+func newEntry(der []byte, precert bool) entry {
+	leaf := make([]byte, 12)
+	binary.BigEndian.PutUint64(leaf[2:10], 1546300800000)
+	if !precert {
+		leaf = append(leaf, uint24Prefixed(der)...)
+		return entry{LeafInput: append(leaf, 0, 0)}
+	}
+
+	binary.BigEndian.PutUint16(leaf[10:12], entryTypePrecert)
+	leaf = append(leaf, make([]byte, 32)...)
+	leaf = append(leaf, uint24Prefixed(nil)...)
+	return entry{LeafInput: append(leaf, 0, 0), ExtraData: append(uint24Prefixed(der), uint24Prefixed(nil)...)}
+}
+
+func newCT(t *testing.T, l, stateFile string) *ct {
+	c := &ct{}
+	if err := c.Configure(&modules.ModuleConfig{Params: map[string]interface{}{"logs": []string{l + "/"}, "statefile": stateFile, "batchsize": 10}}); err != nil {
+		t.Fatalf("could not configure: %s", err)
+	}
+	return c
+}
+
+func tail(t *testing.T, c *ct) []*vautour.Document {
+	ch := make(chan *vautour.Document, 10)
+	if err := c.tail(stopper.NewStopper(), c.Logs[0], ch); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	var ds []*vautour.Document
+	for d := range ch {
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+func TestTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vautour-ct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "ct.json")
+
+	l := &stubLog{}
+	l.add(newEntry(newCertificate(t, "old.example.com"), false))
+	srv := httptest.NewServer(l)
+	defer srv.Close()
+	name := strings.TrimPrefix(srv.URL, "http://")
+
+	// Without cursor, the log is tailed from its current head.
+	c := newCT(t, srv.URL, stateFile)
+	if ds := tail(t, c); len(ds) != 0 {
+		t.Fatalf("expected no document, got %d", len(ds))
+	}
+
+	cert := newCertificate(t, "www.example.com", "www.example.com", "example.com")
+	l.add(newEntry(cert, false))
+	l.add(newEntry(newCertificate(t, "pre.example.com", "pre.example.com"), true))
+	l.add(entry{LeafInput: []byte("garbage")})
+	ds := tail(t, c)
+	if len(ds) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(ds))
+	}
+
+	d := ds[0]
+	if d.ID != name+":1" || d.Title != "www.example.com, example.com" || d.Metadata["precertificate"] != false || d.Size != len(d.Content) {
+		t.Errorf("unexpected document %+v", d)
+	}
+	for _, s := range []string{"Subject: CN=www.example.com\n", "DNS Names: www.example.com, example.com\n", "Not After: 2019-04-01T00:00:00Z\n", "-----BEGIN CERTIFICATE-----\n"} {
+		if !strings.Contains(string(d.Content), s) {
+			t.Errorf("expected %q in the summary:\n%s", s, d.Content)
+		}
+	}
+	if !d.CreatedAt.Equal(time.Unix(1546300800, 0)) {
+		t.Errorf("unexpected timestamp %s", d.CreatedAt)
+	}
+	if d := ds[1]; d.ID != name+":2" || d.Title != "pre.example.com" || d.Metadata["precertificate"] != true || !strings.Contains(string(d.Content), "Precertificate: true\n") {
+		t.Errorf("unexpected document %+v", d)
+	}
+
+	// The cursor persists, and the next run resumes from it.
+	l.add(newEntry(newCertificate(t, "new.example.com"), false))
+	c = newCT(t, srv.URL, stateFile)
+	if v, _ := c.state.Get(c.Logs[0]); v != "4" {
+		t.Errorf("expected the cursor to be 4, got %q", v)
+	}
+	ds = tail(t, c)
+	if len(ds) != 1 || ds[0].ID != name+":4" || ds[0].Title != "new.example.com" {
+		t.Errorf("unexpected documents %+v", ds)
+	}
+	if ds := tail(t, c); len(ds) != 0 {
+		t.Errorf("expected no document, got %d", len(ds))
+	}
+}
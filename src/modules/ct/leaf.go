@@ -0,0 +1,157 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ct
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RFC 6962 structures.
+// https://tools.ietf.org/html/rfc6962#section-3.4
+
+const (
+	entryTypeX509    = 0
+	entryTypePrecert = 1
+)
+
+type sth struct {
+	TreeSize  uint64 `json:"tree_size"`
+	Timestamp uint64 `json:"timestamp"`
+}
+
+type entries struct {
+	Entries []entry `json:"entries"`
+}
+
+type entry struct {
+	LeafInput []byte `json:"leaf_input"`
+	ExtraData []byte `json:"extra_data"`
+}
+
+// certificate is a certificate (or pre-certificate) logged in a CT log.
+type certificate struct {
+	*x509.Certificate
+	Precert   bool
+	Timestamp time.Time
+}
+
+// parse decodes a log entry into the certificate it holds.
+func (e *entry) parse() (*certificate, error) {
+	// MerkleTreeLeaf: version (1), leaf_type (1), then TimestampedEntry:
+	// timestamp (8), entry_type (2), signed_entry.
+	l := e.LeafInput
+	if len(l) < 12 {
+		return nil, errors.New("truncated leaf")
+	}
+	if l[0] != 0 || l[1] != 0 {
+		return nil, fmt.Errorf("unsupported leaf version %d / type %d", l[0], l[1])
+	}
+	c := &certificate{Timestamp: msToTime(binary.BigEndian.Uint64(l[2:10]))}
+
+	var der []byte
+	var err error
+	switch binary.BigEndian.Uint16(l[10:12]) {
+	case entryTypeX509:
+		// ASN.1Cert.
+		der, _, err = readUint24Prefixed(l[12:])
+	case entryTypePrecert:
+		// The signed entry only contains the TBSCertificate, the actual
+		// pre-certificate is the first element of the PrecertChainEntry.
+		c.Precert = true
+		der, _, err = readUint24Prefixed(e.ExtraData)
+	default:
+		return nil, fmt.Errorf("unsupported entry type %d", binary.BigEndian.Uint16(l[10:12]))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Certificate, err = x509.ParseCertificate(der); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func readUint24Prefixed(b []byte) ([]byte, []byte, error) {
+	if len(b) < 3 {
+		return nil, nil, errors.New("truncated length")
+	}
+	n := int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+	if len(b) < 3+n {
+		return nil, nil, errors.New("truncated data")
+	}
+	return b[3 : 3+n], b[3+n:], nil
+}
+
+func msToTime(ms uint64) time.Time {
+	return time.Unix(int64(ms/1000), int64(ms%1000)*int64(time.Millisecond))
+}
+
+// summary returns a human-readable summary of the certificate, followed by its
+// PEM encoding, so that processors can match on names.
+func (c *certificate) summary() []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "Subject: %s\n", c.Subject)
+	fmt.Fprintf(&b, "Issuer: %s\n", c.Issuer)
+	fmt.Fprintf(&b, "Serial: %s\n", c.SerialNumber)
+	fmt.Fprintf(&b, "Not Before: %s\n", c.NotBefore.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Not After: %s\n", c.NotAfter.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Precertificate: %t\n", c.Precert)
+	if len(c.DNSNames) > 0 {
+		fmt.Fprintf(&b, "DNS Names: %s\n", strings.Join(c.DNSNames, ", "))
+	}
+	if len(c.EmailAddresses) > 0 {
+		fmt.Fprintf(&b, "Email Addresses: %s\n", strings.Join(c.EmailAddresses, ", "))
+	}
+	if len(c.IPAddresses) > 0 {
+		var ips []string
+		for _, ip := range c.IPAddresses {
+			ips = append(ips, ip.String())
+		}
+		fmt.Fprintf(&b, "IP Addresses: %s\n", strings.Join(ips, ", "))
+	}
+	b.WriteString("\n")
+	pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+
+	return b.Bytes()
+}
+
+// names returns the subject common name and the DNS names of the certificate.
+func (c *certificate) names() string {
+	names := c.DNSNames
+	if cn := c.Subject.CommonName; cn != "" && !contains(names, cn) {
+		names = append([]string{cn}, names...)
+	}
+	return strings.Join(names, ", ")
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
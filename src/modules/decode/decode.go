@@ -0,0 +1,183 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package decode
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/unpack"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// pathSeparator separates the layers of a decoding path, e.g. base64>gzip>file.txt.
+const pathSeparator = ">"
+
+// decode decodes the base64, hex, URL-encoded, compressed & archived layers of
+// documents, and runs the configured processors on each of them.
+type decode struct {
+	// Processors are the processor modules run on every decoded layer.
+	Processors []string
+	MaxDepth   int
+	// MaxSize is the maximum number of bytes decoded out of a single document.
+	MaxSize int64
+	// MinLength is the minimum length of the encoded runs found in text.
+	MinLength int
+	// MaxLayers is the maximum number of layers processed per document.
+	MaxLayers int
+}
+
+// match is the ProcessedData of a processor's result on a decoded layer.
+type match struct {
+	Path   string
+	Module string
+	Result json.RawMessage
+}
+
+func init() {
+	modules.Register("decode", &decode{})
+}
+
+func (dc *decode) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	dc.MaxDepth = 3
+	dc.MaxSize = 10 * 1024 * 1024
+	dc.MinLength = 64
+	dc.MaxLayers = 100
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, dc); err != nil {
+		return err
+	}
+	if len(dc.Processors) == 0 {
+		return errors.New("no processor configured")
+	}
+
+	return nil
+}
+
+func (dc *decode) Process(d *vautour.Document) error {
+	// Processors are looked up now, as they might be configured after us.
+	var ps []vautour.ProcessorModule
+	for _, n := range dc.Processors {
+		p, err := vautour.Processor(n)
+		if err != nil {
+			return err
+		}
+		if p == vautour.ProcessorModule(dc) {
+			return errors.New("decode cannot run itself")
+		}
+		ps = append(ps, p)
+	}
+
+	s := &session{decode: dc, processors: ps, parent: d, budget: unpack.NewBudget(dc.MaxSize)}
+	s.walk(d.Content, nil, nil, 1)
+
+	if s.layers > 0 {
+		log.WithField("role", "processor").WithField("module", "decode").WithField("item_id", d.ID).WithField("layers", s.layers).Debug("decoded layers")
+	}
+	return nil
+}
+
+// session holds the state of the decoding of a single document.
+type session struct {
+	*decode
+	processors []vautour.ProcessorModule
+	parent     *vautour.Document
+	budget     *unpack.Budget
+	layers     int
+}
+
+// walk processes every layer decoded out of the content, and recurses into
+// them up to the maximum depth. span is the span of the parent's content the
+// content was decoded from, nil for the parent's content itself.
+func (s *session) walk(content []byte, path []string, span *vautour.Span, depth int) {
+	if depth > s.MaxDepth {
+		return
+	}
+
+	for _, l := range layers(content, s.MinLength, s.budget) {
+		if s.MaxLayers > 0 && s.layers >= s.MaxLayers {
+			return
+		}
+		s.layers++
+
+		lPath := append(append([]string(nil), path...), l.Name)
+		lSpan := span
+		if lSpan == nil {
+			lSpan = &l.Span
+		}
+		s.process(l.Content, strings.Join(lPath, pathSeparator), *lSpan)
+		s.walk(l.Content, lPath, lSpan, depth+1)
+	}
+}
+
+// process runs the processors on a decoded layer, as if it was a document of
+// its own, and records their results on the parent document, span being the
+// encoded data the layer comes from.
+func (s *session) process(content []byte, path string, span vautour.Span) {
+	ld := &vautour.Document{
+		ID:              s.parent.ID,
+		Title:           s.parent.Title,
+		User:            s.parent.User,
+		URL:             s.parent.URL,
+		Content:         content,
+		Size:            len(content),
+		CreatedAt:       s.parent.CreatedAt,
+		Depth:           s.parent.Depth,
+		InputModuleName: s.parent.InputModuleName,
+	}
+
+	for i, p := range s.processors {
+		if err := p.Process(ld); err != nil {
+			log.WithField("role", "processor").WithField("module", "decode").WithField("item_id", s.parent.ID).WithField("path", path).WithField("processor", s.Processors[i]).WithError(err).Warn("failed to process decoded layer")
+		}
+	}
+
+	for _, pd := range ld.Processed {
+		j, err := json.Marshal(match{Path: path, Module: pd.Module, Result: pd.RawMessage})
+		if err != nil {
+			continue
+		}
+		rd := vautour.ProcessedData{
+			Module:     "decode",
+			Score:      pd.Score,
+			RawMessage: j,
+		}
+		if len(pd.Spans) > 0 {
+			rd.Spans = []vautour.Span{span}
+		}
+		s.parent.Processed = append(s.parent.Processed, rd)
+	}
+	s.parent.AddTags(ld.Tags...)
+
+	// Sensitive values are encoded in the parent's content, mask all of it.
+	if len(ld.Sensitive) > 0 {
+		s.parent.MarkSensitive(span.Start, span.End)
+	}
+	// The parent's own metadata wins over the layers'.
+	for k, v := range ld.Metadata {
+		if _, ok := s.parent.Metadata[k]; !ok {
+			s.parent.SetMetadata(k, v)
+		}
+	}
+	for _, c := range ld.Children() {
+		s.parent.AddChild(c)
+	}
+}
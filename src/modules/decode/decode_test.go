@@ -0,0 +1,71 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package decode
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/unpack"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+)
+
+// secretProcessor finds the secret in layers, as the detectors do.
+type secretProcessor struct{}
+
+func (secretProcessor) Configure(*modules.ModuleConfig) error { return nil }
+
+func (secretProcessor) Process(d *vautour.Document) error {
+	i := strings.Index(string(d.Content), "hunter2")
+	if i < 0 {
+		return nil
+	}
+	d.MarkSensitive(i, i+len("hunter2"))
+	d.SetMetadata("credentials", []string{"password"})
+	d.SetMetadata("syntax", "text")
+	d.AddChild(&vautour.Document{ID: "child"})
+	d.Processed = append(d.Processed, vautour.ProcessedData{Module: "secrets", Score: 40, Spans: []vautour.Span{{Start: i, End: i + 7}}})
+	return nil
+}
+
+func TestProcessPropagatesLayers(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("user=admin password=hunter2 host=db.internal"))
+	content := "config: " + encoded + "\n"
+	start := strings.Index(content, encoded)
+
+	d := &vautour.Document{ID: "parent", Depth: 1, Content: []byte(content), Metadata: vautour.Metadata{"syntax": "yaml"}}
+	dc := &decode{MaxDepth: 1, MinLength: 16}
+	s := &session{decode: dc, processors: []vautour.ProcessorModule{secretProcessor{}}, parent: d, budget: unpack.NewBudget(1024)}
+	s.walk(d.Content, nil, nil, 1)
+
+	span := vautour.Span{Start: start, End: start + len(encoded)}
+	if !reflect.DeepEqual(d.Sensitive, []vautour.Span{span}) {
+		t.Errorf("expected the encoded span %v to be sensitive, got %v", span, d.Sensitive)
+	}
+	if len(d.Processed) != 1 || d.Processed[0].Score != 40 || !reflect.DeepEqual(d.Processed[0].Spans, []vautour.Span{span}) {
+		t.Errorf("unexpected results %+v", d.Processed)
+	}
+	if d.Metadata["syntax"] != "yaml" || !reflect.DeepEqual(d.Metadata["credentials"], []string{"password"}) {
+		t.Errorf("unexpected metadata %v", d.Metadata)
+	}
+	if c := d.Children(); len(c) != 1 || c[0].ParentID != "parent" || c[0].Depth != 2 || !reflect.DeepEqual(d.ChildIDs, []string{"child"}) {
+		t.Errorf("unexpected children %+v", c)
+	}
+}
@@ -0,0 +1,112 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package decode
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/quentin-m/vautour/src/pkg/unpack"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	layerBase64 = "base64"
+	layerHex    = "hex"
+	layerURL    = "url"
+)
+
+var (
+	base64Run = regexp.MustCompile(`[A-Za-z0-9+/]{16,}={0,2}|[A-Za-z0-9_\-]{16,}={0,2}`)
+	hexRun    = regexp.MustCompile(`(?:[0-9A-Fa-f]{2}){8,}`)
+	urlRun    = regexp.MustCompile(`(?:[^\s%]*%[0-9A-Fa-f]{2}){4,}[^\s%]*`)
+	hexOnly   = regexp.MustCompile(`^[0-9A-Fa-f]+$`)
+
+	// Lengths of hex-encoded MD5, SHA1, SHA256 & SHA512 hashes, which are
+	// not worth decoding.
+	hashLengths = map[int]bool{32: true, 40: true, 64: true, 128: true}
+)
+
+// layer is a piece of content decoded out of its parent.
+type layer struct {
+	// Name is the encoding of the layer, or the name of the file it was
+	// extracted from.
+	Name    string
+	Content []byte
+	// Span is the span of the encoded data in the parent's content.
+	Span vautour.Span
+}
+
+// layers returns the layers that can be decoded out of the content: either
+// the files held in compressed data & archives, or every encoded run found
+// in text.
+func layers(content []byte, minLength int, b *unpack.Budget) []layer {
+	var ls []layer
+	if unpack.Detect(content) != "" {
+		format, files, _ := unpack.Unpack(content, b)
+		for _, f := range files {
+			if unpack.IsStream(format) {
+				f.Name = format
+			}
+			ls = append(ls, layer{Name: f.Name, Content: f.Content, Span: vautour.Span{End: len(content)}})
+		}
+		return ls
+	}
+
+	for _, m := range hexRun.FindAllIndex(content, -1) {
+		run := content[m[0]:m[1]]
+		if len(run) < minLength || hashLengths[len(run)] {
+			continue
+		}
+		if c, err := hex.DecodeString(string(run)); err == nil && b.Take(len(c)) {
+			ls = append(ls, layer{Name: layerHex, Content: c, Span: vautour.Span{Start: m[0], End: m[1]}})
+		}
+	}
+	for _, m := range base64Run.FindAllIndex(content, -1) {
+		run := content[m[0]:m[1]]
+		// Hex runs are valid base64 as well, but were decoded as hex already.
+		if len(run) < minLength || hexOnly.Match(run) {
+			continue
+		}
+		if c, ok := decodeBase64(string(run)); ok && b.Take(len(c)) {
+			ls = append(ls, layer{Name: layerBase64, Content: c, Span: vautour.Span{Start: m[0], End: m[1]}})
+		}
+	}
+	for _, m := range urlRun.FindAllIndex(content, -1) {
+		run := content[m[0]:m[1]]
+		if len(run) < minLength {
+			continue
+		}
+		if c, err := url.PathUnescape(string(run)); err == nil && b.Take(len(c)) {
+			ls = append(ls, layer{Name: layerURL, Content: []byte(c), Span: vautour.Span{Start: m[0], End: m[1]}})
+		}
+	}
+	return ls
+}
+
+// decodeBase64 decodes standard or URL-safe base64, padded or not.
+func decodeBase64(s string) ([]byte, bool) {
+	s = strings.TrimRight(s, "=")
+	for _, enc := range []*base64.Encoding{base64.RawStdEncoding, base64.RawURLEncoding} {
+		if c, err := enc.DecodeString(s); err == nil {
+			return c, true
+		}
+	}
+	return nil, false
+}
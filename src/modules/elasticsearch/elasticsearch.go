@@ -34,6 +34,40 @@ const (
 	"settings":{
 		"number_of_shards": %d,
 		"number_of_replicas": %d
+	},
+	"mappings":{
+		"document":{
+			"dynamic_templates":[
+				{
+					"metadata_strings":{
+						"path_match": "Metadata.*",
+						"match_mapping_type": "string",
+						"mapping": {"type": "keyword", "ignore_above": 1024}
+					}
+				}
+			],
+			"properties":{
+				"Tags": {"type": "keyword"},
+				"ParentID": {"type": "keyword"},
+				"ChildIDs": {"type": "keyword"},
+				"ClusterID": {"type": "keyword"},
+				"Severity": {"type": "keyword"},
+				"Similar": {
+					"properties": {
+						"ID": {"type": "keyword"}
+					}
+				},
+				"Class": {
+					"properties": {
+						"FileType": {"type": "keyword"},
+						"MIME": {"type": "keyword"},
+						"Language": {"type": "keyword"},
+						"Charset": {"type": "keyword"}
+					}
+				},
+				"Metadata": {"type": "object"}
+			}
+		}
 	}
 }
 `
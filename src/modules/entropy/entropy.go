@@ -0,0 +1,272 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package entropy
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/secret"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	charsetBase64 = "base64"
+	charsetHex    = "hex"
+)
+
+var (
+	// Runs of base64 (standard & URL-safe) and hex characters.
+	base64Run = regexp.MustCompile(`[A-Za-z0-9+/_\-]{16,}={0,2}`)
+	hexRun    = regexp.MustCompile(`[A-Fa-f0-9]{16,}`)
+
+	uuid = regexp.MustCompile(`^[A-Fa-f0-9]{8}-[A-Fa-f0-9]{4}-[A-Fa-f0-9]{4}-[A-Fa-f0-9]{4}-[A-Fa-f0-9]{12}$`)
+	// Lines of lockfiles (npm, yarn, go.sum, Cargo.lock, Pipfile.lock, ...),
+	// whose checksums are high-entropy by nature.
+	lockfileLine = regexp.MustCompile(`(?i)(integrity|checksum|resolved|"hash"|sha(1|256|384|512)[-:=]|\sh1:|/go\.mod\s)`)
+	// Markers of m3u8 playlists, whose stream URLs are full of tokens.
+	m3u8Markers = [][]byte{[]byte("#EXTM3U"), []byte("#EXTINF")}
+)
+
+// entropy looks for high-entropy strings, such as API keys with no fixed
+// prefix, that regular expressions cannot reasonably describe.
+type entropy struct {
+	Base64 charsetConfig
+	Hex    charsetConfig
+
+	// Keywords found within KeywordWindow bytes before a candidate (e.g. on
+	// the same `api_key = ...` line) lower its threshold by KeywordBoost.
+	Keywords      []string
+	KeywordWindow int
+	KeywordBoost  float64
+
+	// Score is the score of the documents with candidates, KeywordScore of
+	// those with candidates next to a keyword.
+	Score        int
+	KeywordScore int
+	// MaxFindings is the maximum number of candidates reported per document.
+	MaxFindings int
+}
+
+type charsetConfig struct {
+	MinLength int
+	MaxLength int
+	Threshold float64
+}
+
+// candidate is the ProcessedData of a high-entropy string.
+type candidate struct {
+	Charset string
+	Offset  int
+	Line    int
+	Length  int
+	Entropy float64
+	Value   string
+	Keyword string `json:",omitempty"`
+}
+
+func init() {
+	modules.Register("entropy", &entropy{})
+}
+
+func (e *entropy) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	e.Base64 = charsetConfig{MinLength: 20, MaxLength: 100, Threshold: 4.5}
+	e.Hex = charsetConfig{MinLength: 32, MaxLength: 128, Threshold: 3.0}
+	e.Keywords = []string{"key", "secret", "token", "passw", "auth", "credential", "api"}
+	e.KeywordWindow = 40
+	e.KeywordBoost = 0.5
+	e.Score = 3
+	e.KeywordScore = 20
+	e.MaxFindings = 20
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, e); err != nil {
+		return err
+	}
+	for i := range e.Keywords {
+		e.Keywords[i] = strings.ToLower(e.Keywords[i])
+	}
+
+	return nil
+}
+
+func (e *entropy) Process(d *vautour.Document) error {
+	for _, m := range m3u8Markers {
+		if bytes.Contains(d.Content, m) {
+			return nil
+		}
+	}
+
+	var cs []*candidate
+	full := func() bool { return e.MaxFindings > 0 && len(cs) >= e.MaxFindings }
+	for _, c := range []struct {
+		name string
+		re   *regexp.Regexp
+		cfg  charsetConfig
+	}{
+		// Hex first, so that hex strings are not reported again as base64.
+		{charsetHex, hexRun, e.Hex},
+		{charsetBase64, base64Run, e.Base64},
+	} {
+		if full() {
+			break
+		}
+
+		// The matches are in offset order: walk the candidates of the previous
+		// charsets alongside them to skip the overlapping ones.
+		prev := append([]*candidate(nil), cs...)
+		sort.Slice(prev, func(i, j int) bool { return prev[i].Offset < prev[j].Offset })
+		k := 0
+		for _, m := range c.re.FindAllIndex(d.Content, -1) {
+			// Hex runs within longer base64 ones (e.g. tokens starting with hex
+			// characters) are left to the base64 charset.
+			if c.name == charsetHex && !bounded(d.Content, m[0], m[1]) {
+				continue
+			}
+			for k < len(prev) && prev[k].Offset+prev[k].Length <= m[0] {
+				k++
+			}
+			if k < len(prev) && prev[k].Offset < m[1] {
+				continue
+			}
+			if cd := e.check(d.Content, m[0], m[1], c.name, c.cfg); cd != nil {
+				cs = append(cs, cd)
+				if full() {
+					break
+				}
+			}
+		}
+	}
+
+	for _, cd := range cs {
+		j, err := json.Marshal(cd)
+		if err != nil {
+			log.WithField("role", "processor").WithField("module", "entropy").WithField("item_id", d.ID).Warn("failed to marshal candidate")
+			continue
+		}
+		score := e.Score
+		if cd.Keyword != "" {
+			score = e.KeywordScore
+		}
+		d.Processed = append(d.Processed, vautour.ProcessedData{
+			Module:     "entropy",
+			Score:      score,
+			Spans:      []vautour.Span{{Start: cd.Offset, End: cd.Offset + cd.Length}},
+			RawMessage: j,
+		})
+		d.MarkSensitive(cd.Offset, cd.Offset+cd.Length)
+	}
+	if len(cs) > 0 {
+		log.WithField("role", "processor").WithField("module", "entropy").WithField("item_id", d.ID).WithField("candidates", len(cs)).Debug("found high-entropy strings")
+	}
+
+	return nil
+}
+
+// bounded returns whether content[start:end] is not surrounded by base64
+// characters.
+func bounded(content []byte, start, end int) bool {
+	if start > 0 && base64Char(content[start-1]) {
+		return false
+	}
+	return end >= len(content) || !base64Char(content[end])
+}
+
+func base64Char(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '+' || c == '/' || c == '_' || c == '-'
+}
+
+// check returns the candidate at content[start:end] if it passes the
+// thresholds and false-positive filters of the charset, nil otherwise.
+func (e *entropy) check(content []byte, start, end int, charset string, cfg charsetConfig) *candidate {
+	value := string(content[start:end])
+	if len(value) < cfg.MinLength || (cfg.MaxLength > 0 && len(value) > cfg.MaxLength) {
+		return nil
+	}
+	if charset == charsetBase64 && !isMixed(value) {
+		// Words, paths or identifiers, e.g. `some_long_function_name`.
+		return nil
+	}
+	if uuid.MatchString(value) {
+		return nil
+	}
+
+	lineStart := bytes.LastIndexByte(content[:start], '\n') + 1
+	lineEnd := bytes.IndexByte(content[end:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(content)
+	} else {
+		lineEnd += end
+	}
+	if lockfileLine.Match(content[lineStart:lineEnd]) {
+		return nil
+	}
+
+	threshold := cfg.Threshold
+	keyword := e.keyword(content, start)
+	if keyword != "" {
+		threshold -= e.KeywordBoost
+	}
+	h := secret.Entropy(value)
+	if h < threshold {
+		return nil
+	}
+
+	return &candidate{
+		Charset: charset,
+		Offset:  start,
+		Line:    bytes.Count(content[:start], []byte("\n")) + 1,
+		Length:  len(value),
+		Entropy: h,
+		Value:   secret.Redact(value),
+		Keyword: keyword,
+	}
+}
+
+// keyword returns the first keyword found right before the given offset.
+func (e *entropy) keyword(content []byte, offset int) string {
+	from := offset - e.KeywordWindow
+	if from < 0 {
+		from = 0
+	}
+	window := bytes.ToLower(content[from:offset])
+	for _, k := range e.Keywords {
+		if bytes.Contains(window, []byte(k)) {
+			return k
+		}
+	}
+	return ""
+}
+
+// isMixed returns whether s mixes digits and letters, as generated keys do.
+func isMixed(s string) bool {
+	var digit, letter bool
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digit = true
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			letter = true
+		}
+	}
+	return digit && letter
+}
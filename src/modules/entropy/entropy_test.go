@@ -0,0 +1,86 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package entropy
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+)
+
+func newEntropy(t *testing.T, params map[string]interface{}) *entropy {
+	e := &entropy{}
+	if err := e.Configure(&modules.ModuleConfig{Params: params}); err != nil {
+		t.Fatalf("could not configure: %s", err)
+	}
+	return e
+}
+
+func TestProcessSkipsOverlaps(t *testing.T) {
+	e := newEntropy(t, nil)
+	d := &vautour.Document{Content: []byte("sha: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08\n" +
+		"token: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08XyZqR7mPwK2nLs9TvB4\n" +
+		"key = Zm9vYmFyYmF6cXV4MTIzNDU2Nzg5MGFiY2RlZmdoaWprbG1ub3A=\n")}
+	if err := e.Process(d); err != nil {
+		t.Fatalf("could not process: %s", err)
+	}
+
+	var charsets []string
+	for _, p := range d.Processed {
+		var cd candidate
+		if err := json.Unmarshal(p.RawMessage, &cd); err != nil {
+			t.Fatalf("could not unmarshal: %s", err)
+		}
+		charsets = append(charsets, fmt.Sprintf("%s@%d", cd.Charset, cd.Line))
+	}
+	// The hex strings are not reported again as base64, and the hex prefix of
+	// the token is left to the base64 charset.
+	if got := strings.Join(charsets, ","); got != "hex@1,base64@2,base64@3" {
+		t.Errorf("unexpected candidates %s", got)
+	}
+	token := strings.Index(string(d.Content), "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08XyZq")
+	found := false
+	for _, s := range d.Sensitive {
+		if s.Start == token && string(d.Content[s.Start:s.End]) == "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08XyZqR7mPwK2nLs9TvB4" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("the whole token is not sensitive: %v", d.Sensitive)
+	}
+}
+
+func TestProcessMaxFindings(t *testing.T) {
+	e := newEntropy(t, map[string]interface{}{"maxfindings": 5})
+	var b strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&b, "%x\n", sha256.Sum256([]byte(strconv.Itoa(i))))
+	}
+	d := &vautour.Document{Content: []byte(b.String())}
+	if err := e.Process(d); err != nil {
+		t.Fatalf("could not process: %s", err)
+	}
+	if len(d.Processed) != 5 || len(d.Sensitive) != 5 {
+		t.Errorf("expected 5 candidates, got %d (%d sensitive spans)", len(d.Processed), len(d.Sensitive))
+	}
+}
@@ -0,0 +1,172 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package fuzzyhash
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/tlsh"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"time"
+)
+
+const (
+	// index is the name of the index of the digests in the queue backend.
+	index = "vautour:fuzzyhash"
+	// bandSize is the number of hexadecimal characters of the digests' body
+	// per index key. Near duplicates are found as long as they share one band.
+	bandSize = 4
+)
+
+// fuzzyhash computes the TLSH digest of documents, and clusters them with the
+// similar documents seen before, which digests are indexed in the queue
+// backend.
+type fuzzyhash struct {
+	// Threshold is the minimum similarity (0-100) of near duplicates, where the
+	// similarity is 100 minus the TLSH distance.
+	Threshold int
+	// Retention is how long the digests are kept in the index.
+	Retention time.Duration
+	// MaxSimilar is the maximum number of similar documents listed.
+	MaxSimilar int
+	// MaxCandidates is the maximum number of digests compared per index key.
+	MaxCandidates int
+}
+
+// entry is a document in the index.
+type entry struct {
+	ID        string
+	ClusterID string
+	Digest    string
+}
+
+// digest is the ProcessedData of a document.
+type digest struct {
+	Algorithm string
+	Digest    string
+}
+
+func init() {
+	modules.Register("fuzzyhash", &fuzzyhash{})
+}
+
+func (f *fuzzyhash) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	f.Threshold = 40
+	f.Retention = 30 * 24 * time.Hour
+	f.MaxSimilar = 5
+	f.MaxCandidates = 1000
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, f); err != nil {
+		return err
+	}
+	if f.Threshold < 0 || f.Threshold > 100 {
+		return fmt.Errorf("threshold must be between 0 and 100, got %d", f.Threshold)
+	}
+
+	return nil
+}
+
+func (f *fuzzyhash) Process(d *vautour.Document) error {
+	h, err := tlsh.Hash(d.Content)
+	if err != nil {
+		// Too short or uniform contents have no meaningful digest.
+		log.WithField("role", "processor").WithField("module", "fuzzyhash").WithField("item_id", d.ID).WithError(err).Debug("skipped document")
+		return nil
+	}
+
+	idx, err := vautour.Index()
+	if err != nil {
+		return err
+	}
+	keys := bands(h)
+
+	// Find the similar documents seen before.
+	values, err := idx.LookupIndex(index, keys, f.MaxCandidates)
+	if err != nil {
+		return err
+	}
+	d.Similar = nil
+	d.ClusterID = d.ID
+	cluster := make(map[string]string)
+	for _, v := range values {
+		var e entry
+		if err := json.Unmarshal([]byte(v), &e); err != nil || e.ID == d.ID {
+			continue
+		}
+		eh, err := tlsh.Parse(e.Digest)
+		if err != nil {
+			continue
+		}
+		score := 100 - h.Distance(eh)
+		if score < f.Threshold {
+			continue
+		}
+		if _, ok := cluster[e.ID]; !ok {
+			d.Similar = append(d.Similar, vautour.Similarity{ID: e.ID, Score: score})
+		}
+		cluster[e.ID] = e.ClusterID
+	}
+	sort.SliceStable(d.Similar, func(i, j int) bool { return d.Similar[i].Score > d.Similar[j].Score })
+	if len(d.Similar) > 0 {
+		// Join the cluster of the most similar document.
+		d.ClusterID = cluster[d.Similar[0].ID]
+	}
+	if d.ClusterID != d.ID {
+		d.AddTags("near-duplicate")
+	}
+	if f.MaxSimilar > 0 && len(d.Similar) > f.MaxSimilar {
+		d.Similar = d.Similar[:f.MaxSimilar]
+	}
+
+	// Index the document.
+	e, err := json.Marshal(entry{ID: d.ID, ClusterID: d.ClusterID, Digest: h.String()})
+	if err != nil {
+		return err
+	}
+	if err := idx.AddToIndex(index, keys, string(e), f.Retention); err != nil {
+		return err
+	}
+
+	j, err := json.Marshal(digest{Algorithm: "tlsh", Digest: h.String()})
+	if err != nil {
+		return err
+	}
+	d.Processed = append(d.Processed, vautour.ProcessedData{
+		Module:     "fuzzyhash",
+		RawMessage: j,
+	})
+	if len(d.Similar) > 0 {
+		log.WithField("role", "processor").WithField("module", "fuzzyhash").WithField("item_id", d.ID).WithField("cluster_id", d.ClusterID).WithField("similar", len(d.Similar)).Debug("found near duplicates")
+	}
+
+	return nil
+}
+
+// bands splits the body of the digest into the keys under which it is indexed.
+func bands(h *tlsh.Digest) []string {
+	body := h.Body()
+	keys := make([]string, 0, len(body)/bandSize)
+	for i := 0; i+bandSize <= len(body); i += bandSize {
+		keys = append(keys, fmt.Sprintf("%d:%s", i/bandSize, body[i:i+bandSize]))
+	}
+	return keys
+}
@@ -0,0 +1,343 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/state"
+	"github.com/quentin-m/vautour/src/pkg/stopper"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/diff"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// git walks the history of local git repositories (e.g. mirrors of an
+// organization's repositories), and yields the lines added by each commit.
+type git struct {
+	Interval     time.Duration
+	Repositories []string
+	// Refs are the references whose history is walked, e.g. HEAD or refs/heads/master.
+	Refs []string
+	// MaxSize is the maximum size of the content of a document, which is truncated beyond.
+	MaxSize int
+	// StateFile persists the commits already listed (as the heads their
+	// history was walked from, per remote), in memory only if empty.
+	StateFile string
+
+	state *state.State
+}
+
+func init() {
+	modules.Register("git", &git{})
+}
+
+func (g *git) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	g.Interval = 5 * time.Minute
+	g.Refs = []string{"HEAD"}
+	g.MaxSize = 1024 * 1024
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, g); err != nil {
+		return err
+	}
+	if len(g.Repositories) == 0 {
+		return errors.New("no repository configured")
+	}
+
+	s, err := state.Open(g.StateFile)
+	if err != nil {
+		return fmt.Errorf("could not load state file: %s", err)
+	}
+	g.state = s
+
+	return nil
+}
+
+func (g *git) List(st *stopper.Stopper, ch chan *vautour.Document) error {
+	if g.Interval <= 0 {
+		<-st.Chan()
+		return nil
+	}
+
+	// Walk right away, and then on every tick.
+	t := time.NewTicker(g.Interval)
+	defer t.Stop()
+	for {
+		for _, r := range g.Repositories {
+			for _, ref := range g.Refs {
+				if err := g.walk(st, r, ref, ch); err != nil {
+					log.WithField("role", "lister").WithField("module", "git").WithField("repository", r).WithField("ref", ref).WithError(err).Warn("failed to list new commits")
+				}
+			}
+		}
+
+		// Wait for next loop.
+		select {
+		case <-st.Chan():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// walk yields the commits reachable from the reference that have not been
+// listed yet, and records the reference's new head.
+func (g *git) walk(st *stopper.Stopper, path, ref string, ch chan *vautour.Document) error {
+	s, err := open(path)
+	if err != nil {
+		return err
+	}
+
+	r, err := storer.ResolveReference(s, plumbing.ReferenceName(ref))
+	if err != nil {
+		return fmt.Errorf("could not resolve reference: %s", err)
+	}
+	head, err := object.GetCommit(s, r.Hash())
+	if err != nil {
+		return err
+	}
+
+	// Skip the history of the heads listed already, from any reference or
+	// mirror of the remote, so that merges & force-pushes only yield the new
+	// commits.
+	remote := remoteURL(s, path)
+	if v, _ := g.state.Get(remote); strings.Contains(v, head.Hash.String()) {
+		return nil
+	}
+	seen, heads, err := g.seen(s, remote)
+	if err != nil {
+		return err
+	}
+	if seen[head.Hash] {
+		return nil
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), ".git")
+	err = object.NewCommitPreorderIter(head, seen, nil).ForEach(func(c *object.Commit) error {
+		if !st.IsRunning() {
+			return storer.ErrStop
+		}
+
+		d, err := g.document(remote, name, c)
+		if err != nil {
+			log.WithField("role", "lister").WithField("module", "git").WithField("repository", path).WithField("item_id", c.Hash.String()).WithError(err).Warn("failed to diff commit")
+			return nil
+		}
+		if d != nil {
+			ch <- d
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !st.IsRunning() {
+		// The walk was interrupted, start over next time.
+		return nil
+	}
+
+	return g.state.Set(remote, strings.Join(append([]string{head.Hash.String()}, heads...), " "))
+}
+
+// seen returns the commits reachable from the heads listed already for the
+// remote, as well as those heads, minus the ones that are reachable from
+// others or that do not exist anymore.
+func (g *git) seen(s *filesystem.Storage, remote string) (map[plumbing.Hash]bool, []string, error) {
+	seen := make(map[plumbing.Hash]bool)
+	v, _ := g.state.Get(remote)
+
+	// Heads are stored from the most recent, whose history holds the older
+	// ones unless the reference was rewritten.
+	var heads []string
+	for _, h := range strings.Fields(v) {
+		hash := plumbing.NewHash(h)
+		if seen[hash] {
+			continue
+		}
+		c, err := object.GetCommit(s, hash)
+		if err != nil {
+			// Garbage collected after a force-push.
+			continue
+		}
+		err = object.NewCommitPreorderIter(c, seen, nil).ForEach(func(c *object.Commit) error {
+			seen[c.Hash] = true
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		heads = append(heads, h)
+	}
+	return seen, heads, nil
+}
+
+// document builds a document out of the lines added by the commit, or returns
+// nil if it added none. Its ID is prefixed with the remote, as forks share
+// their commits.
+func (g *git) document(remote, repo string, c *object.Commit) (*vautour.Document, error) {
+	// Merge commits are skipped, their changes are listed with the merged commits.
+	if c.NumParents() > 1 {
+		return nil, nil
+	}
+
+	added, err := addedLines(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	var paths []string
+	for p := range added {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var b bytes.Buffer
+	for _, p := range paths {
+		fmt.Fprintf(&b, "+++ %s\n%s\n", p, strings.TrimSuffix(added[p], "\n"))
+	}
+	content := b.Bytes()
+	if g.MaxSize > 0 && len(content) > g.MaxSize {
+		content = content[:g.MaxSize]
+	}
+
+	title := fmt.Sprintf("%s/%s", repo, paths[0])
+	if len(paths) > 1 {
+		title = fmt.Sprintf("%s (+%d files)", title, len(paths)-1)
+	}
+
+	d := &vautour.Document{
+		ID:        remote + "@" + c.Hash.String(),
+		Title:     title,
+		User:      fmt.Sprintf("%s <%s>", c.Author.Name, c.Author.Email),
+		Content:   content,
+		Size:      len(content),
+		CreatedAt: c.Committer.When,
+	}
+	d.SetMetadata("repository", repo)
+	d.SetMetadata("remote", remote)
+	d.SetMetadata("files", paths)
+	d.SetMetadata("message", strings.TrimSpace(c.Message))
+
+	return d, nil
+}
+
+// addedLines returns, for each text file, the lines added by the commit
+// compared to its parent (or all of the lines of a root commit).
+func addedLines(c *object.Commit) (map[string]string, error) {
+	added := make(map[string]string)
+
+	if c.NumParents() == 0 {
+		files, err := c.Files()
+		if err != nil {
+			return nil, err
+		}
+		err = files.ForEach(func(f *object.File) error {
+			if bin, err := f.IsBinary(); err != nil || bin {
+				return err
+			}
+			content, err := f.Contents()
+			if err != nil {
+				return err
+			}
+			added[f.Name] = content
+			return nil
+		})
+		return added, err
+	}
+
+	parent, err := c.Parent(0)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := parent.Patch(c)
+	if err != nil {
+		return nil, err
+	}
+	for _, fp := range patch.FilePatches() {
+		_, to := fp.Files()
+		if fp.IsBinary() || to == nil {
+			continue
+		}
+
+		var b strings.Builder
+		for _, chunk := range fp.Chunks() {
+			if chunk.Type() == diff.Add {
+				b.WriteString(chunk.Content())
+			}
+		}
+		if b.Len() > 0 {
+			added[to.Path()] = b.String()
+		}
+	}
+	return added, nil
+}
+
+// Scrape does nothing, as commits are diffed while listing.
+func (g *git) Scrape(d *vautour.Document) error {
+	return nil
+}
+
+// open opens the repository at the given path, either bare (e.g. a mirror) or
+// with a working tree.
+func open(path string) (*filesystem.Storage, error) {
+	dir := filepath.Join(path, ".git")
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		dir = path
+	}
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil {
+		return nil, fmt.Errorf("not a git repository: %s", path)
+	}
+
+	return filesystem.NewStorage(osfs.New(dir), cache.NewObjectLRUDefault()), nil
+}
+
+// remoteURL returns the URL of the origin remote of the repository, without
+// credentials, or its absolute path if it has none.
+func remoteURL(s *filesystem.Storage, path string) string {
+	if cfg, err := s.Config(); err == nil {
+		if r, ok := cfg.Remotes["origin"]; ok && len(r.URLs) > 0 {
+			if u, err := url.Parse(r.URLs[0]); err == nil && u.User != nil {
+				u.User = nil
+				return u.String()
+			}
+			return r.URLs[0]
+		}
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,350 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package htmlscrape
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/stopper"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	defaultUserAgent = "Mozilla/5.0 (compatible; Vautour; +https://github.com/quentin-m/vautour)"
+	maxBodySize      = 10 * 1024 * 1024
+)
+
+// htmlscrape lists documents from the "recent pastes" HTML page of sites that
+// have no API, using CSS selectors.
+type htmlscrape struct {
+	Interval time.Duration
+	Timeout  time.Duration
+	// Delay is the minimum time between two requests to the site.
+	Delay     time.Duration
+	UserAgent string
+	Headers   map[string]string
+
+	Listing  listingConfig
+	Scraping scrapeConfig `yaml:"scrape"`
+
+	items, link, title, user, createdAt, content *selector
+	metadata                                     map[string]*selector
+	id                                           *regexp.Regexp
+	scrapeURL                                    *template.Template
+
+	client *http.Client
+	lastM  sync.Mutex
+	last   time.Time
+}
+
+type listingConfig struct {
+	URL string
+
+	// Items selects every listed item on the page, the other selectors are
+	// relative to each item.
+	Items     string
+	Link      string
+	Title     string
+	User      string
+	CreatedAt string
+	// Metadata maps metadata keys onto selectors.
+	Metadata map[string]string
+
+	// ID is a regular expression applied to the absolute link of the item,
+	// whose first group is the item's ID. The last path segment is used if empty.
+	ID string
+	// TimeFormat is the layout of CreatedAt, when not a UNIX timestamp.
+	TimeFormat string
+}
+
+type scrapeConfig struct {
+	// URL is a text/template executed against the listed document pointing to
+	// the raw view of the item (e.g. `https://example.com/raw/{{.ID}}`). The
+	// document's URL is used if empty.
+	URL string
+	// Content selects the content in the scraped page. The whole body is used
+	// if empty.
+	Content string
+}
+
+func init() {
+	modules.Register("htmlscrape", &htmlscrape{})
+}
+
+func (h *htmlscrape) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	h.Interval = 60 * time.Second
+	h.Timeout = 10 * time.Second
+	h.Delay = 2 * time.Second
+	h.UserAgent = defaultUserAgent
+	h.Listing.Link = "a@href"
+	h.Listing.TimeFormat = time.RFC3339
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, h); err != nil {
+		return err
+	}
+	if h.Interval > 0 && (h.Listing.URL == "" || h.Listing.Items == "") {
+		return errors.New("missing listing url or items selector")
+	}
+
+	// Compile selectors.
+	for _, s := range []struct {
+		dst **selector
+		src string
+	}{
+		{&h.items, h.Listing.Items},
+		{&h.link, h.Listing.Link},
+		{&h.title, h.Listing.Title},
+		{&h.user, h.Listing.User},
+		{&h.createdAt, h.Listing.CreatedAt},
+		{&h.content, h.Scraping.Content},
+	} {
+		sel, err := compileSelector(s.src)
+		if err != nil {
+			return err
+		}
+		*s.dst = sel
+	}
+	h.metadata = make(map[string]*selector)
+	for k, src := range h.Listing.Metadata {
+		sel, err := compileSelector(src)
+		if err != nil {
+			return err
+		}
+		h.metadata[k] = sel
+	}
+
+	if h.Listing.ID != "" {
+		r, err := regexp.Compile(h.Listing.ID)
+		if err != nil {
+			return fmt.Errorf("invalid id regular expression: %s", err)
+		}
+		h.id = r
+	}
+	if h.Scraping.URL != "" {
+		t, err := template.New("scrape").Parse(h.Scraping.URL)
+		if err != nil {
+			return fmt.Errorf("invalid scrape url template: %s", err)
+		}
+		h.scrapeURL = t
+	}
+
+	h.client = &http.Client{Timeout: h.Timeout}
+
+	return nil
+}
+
+func (h *htmlscrape) List(st *stopper.Stopper, ch chan *vautour.Document) error {
+	if h.Interval <= 0 {
+		<-st.Chan()
+		return nil
+	}
+
+	t := time.NewTicker(h.Interval)
+	defer t.Stop()
+	for {
+		// Wait for next loop.
+		select {
+		case <-st.Chan():
+			return nil
+		case <-t.C:
+		}
+
+		// List.
+		ds, err := h.list()
+		if err != nil {
+			log.WithField("role", "lister").WithField("module", "htmlscrape").WithField("url", h.Listing.URL).WithError(err).Warn("failed to list new documents")
+			continue
+		}
+		for _, d := range ds {
+			ch <- d
+		}
+	}
+}
+
+func (h *htmlscrape) list() ([]*vautour.Document, error) {
+	base, err := url.Parse(h.Listing.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := h.get(h.Listing.URL)
+	if err != nil {
+		return nil, err
+	}
+	root, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse listing: %s", err)
+	}
+
+	var ds []*vautour.Document
+	for _, item := range h.items.nodes(root) {
+		d, err := h.document(base, item)
+		if err != nil {
+			log.WithField("role", "lister").WithField("module", "htmlscrape").WithField("url", h.Listing.URL).WithError(err).Debug("skipped listed item")
+			continue
+		}
+		ds = append(ds, d)
+	}
+	return ds, nil
+}
+
+// document builds a vautour.Document out of a listed item.
+func (h *htmlscrape) document(base *url.URL, item *html.Node) (*vautour.Document, error) {
+	link := h.link.value(item)
+	if link == "" {
+		return nil, errors.New("no link found")
+	}
+	u, err := base.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("invalid link %q: %s", link, err)
+	}
+	// Links come from third-party pages, only follow them on the web.
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme in link %q", link)
+	}
+
+	d := &vautour.Document{
+		URL:   u.String(),
+		Title: h.title.value(item),
+		User:  h.user.value(item),
+	}
+
+	if h.id != nil {
+		m := h.id.FindStringSubmatch(d.URL)
+		if len(m) < 2 {
+			return nil, fmt.Errorf("no id found in %q", d.URL)
+		}
+		d.ID = m[1]
+	} else {
+		d.ID = path.Base(u.Path)
+	}
+	if d.ID == "" || d.ID == "/" || d.ID == "." {
+		return nil, fmt.Errorf("no id found in %q", d.URL)
+	}
+
+	if s := h.createdAt.value(item); s != "" {
+		if q, err := strconv.ParseInt(s, 10, 64); err == nil {
+			d.CreatedAt = time.Unix(q, 0)
+		} else if t, err := time.Parse(h.Listing.TimeFormat, s); err == nil {
+			d.CreatedAt = t
+		}
+	}
+	for k, sel := range h.metadata {
+		if v := sel.value(item); v != "" {
+			d.SetMetadata(k, v)
+		}
+	}
+
+	return d, nil
+}
+
+func (h *htmlscrape) Scrape(d *vautour.Document) error {
+	u := d.URL
+	if h.scrapeURL != nil {
+		var b bytes.Buffer
+		if err := h.scrapeURL.Execute(&b, d); err != nil {
+			return fmt.Errorf("failed to render scrape url: %s", err)
+		}
+		u = b.String()
+	}
+	if u == "" {
+		return errors.New("no url to scrape")
+	}
+
+	body, err := h.get(u)
+	if err != nil {
+		log.WithField("role", "scraper").WithField("module", "htmlscrape").WithField("item_id", d.ID).WithError(err).Warn("failed to scrape document")
+		return err
+	}
+
+	if h.content != nil {
+		root, err := html.Parse(bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to parse scraped document: %s", err)
+		}
+		ns := h.content.nodes(root)
+		if len(ns) == 0 {
+			return fmt.Errorf("content %q not found in scraped document", h.Scraping.Content)
+		}
+
+		var parts []string
+		for _, n := range ns {
+			if h.content.attr != "" {
+				parts = append(parts, attr(n, h.content.attr))
+			} else {
+				parts = append(parts, text(n))
+			}
+		}
+		body = []byte(strings.Join(parts, "\n"))
+	}
+
+	d.Content = body
+	d.Size = len(d.Content)
+
+	return nil
+}
+
+func (h *htmlscrape) get(u string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", h.UserAgent)
+	for k, v := range h.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+
+	h.wait()
+	res, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(res.Body, maxBodySize))
+}
+
+// wait blocks until the politeness delay since the previous request to the
+// site has elapsed. Requests from the lister & the scrapers are serialized.
+func (h *htmlscrape) wait() {
+	h.lastM.Lock()
+	defer h.lastM.Unlock()
+
+	time.Sleep(time.Until(h.last.Add(h.Delay)))
+	h.last = time.Now()
+}
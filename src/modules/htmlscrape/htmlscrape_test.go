@@ -0,0 +1,107 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package htmlscrape
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+)
+
+const listingFixture = `<html><body><ul>
+<li class="paste"><a href="/p/abc123">First</a><span class="user">alice</span></li>
+<li class="paste"><a href="file:///etc/passwd">Local</a></li>
+<li class="paste"><a href="javascript:alert(1)">Script</a></li>
+<li class="paste"><a href="https://paste.example.com/p/def456">Second</a></li>
+</ul></body></html>`
+
+func newFixtureServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/archive", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, listingFixture)
+	})
+	mux.HandleFunc("/p/abc123", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><pre id="content">hello</pre></body></html>`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func newScraper(t *testing.T, listingURL string) *htmlscrape {
+	h := &htmlscrape{}
+	err := h.Configure(&modules.ModuleConfig{Params: map[string]interface{}{
+		"delay": "0s",
+		"listing": map[string]interface{}{
+			"url":   listingURL,
+			"items": "li.paste",
+			"title": "a",
+			"user":  "span.user",
+		},
+		"scrape": map[string]interface{}{
+			"content": "pre#content",
+		},
+	}})
+	if err != nil {
+		t.Fatalf("could not configure: %s", err)
+	}
+	return h
+}
+
+func TestList(t *testing.T) {
+	srv := newFixtureServer()
+	defer srv.Close()
+	h := newScraper(t, srv.URL+"/archive")
+
+	ds, err := h.list()
+	if err != nil {
+		t.Fatalf("could not list: %s", err)
+	}
+
+	// Links whose scheme is not http(s) are skipped.
+	if len(ds) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(ds))
+	}
+	if ds[0].ID != "abc123" || ds[0].URL != srv.URL+"/p/abc123" || ds[0].Title != "First" || ds[0].User != "alice" {
+		t.Errorf("unexpected first document: %+v", ds[0])
+	}
+	if ds[1].ID != "def456" || ds[1].URL != "https://paste.example.com/p/def456" {
+		t.Errorf("unexpected second document: %+v", ds[1])
+	}
+}
+
+func TestScrape(t *testing.T) {
+	srv := newFixtureServer()
+	defer srv.Close()
+	h := newScraper(t, srv.URL+"/archive")
+
+	d := &vautour.Document{ID: "abc123", URL: srv.URL + "/p/abc123"}
+	if err := h.Scrape(d); err != nil {
+		t.Fatalf("could not scrape: %s", err)
+	}
+	if string(d.Content) != "hello" || d.Size != 5 {
+		t.Errorf("unexpected content %q (size %d)", d.Content, d.Size)
+	}
+
+	// Local files cannot be read, even through the scrape url.
+	d = &vautour.Document{ID: "passwd", URL: "file:///etc/passwd"}
+	if err := h.Scrape(d); err == nil {
+		t.Errorf("expected file:// urls to fail, got %q", d.Content)
+	}
+}
@@ -0,0 +1,109 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package htmlscrape
+
+import (
+	"fmt"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"strings"
+)
+
+// selector extracts a value out of an HTML node. It is configured as a CSS
+// selector, optionally followed by `@attribute` to read an attribute instead
+// of the text (e.g. `td.title a@href`). An empty CSS selector designates the
+// node itself (e.g. `@href`).
+type selector struct {
+	css  cascadia.Selector
+	attr string
+}
+
+func compileSelector(s string) (*selector, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	sel := &selector{}
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		sel.attr = strings.TrimSpace(s[i+1:])
+		s = s[:i]
+	}
+	if s = strings.TrimSpace(s); s != "" {
+		css, err := cascadia.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %s", s, err)
+		}
+		sel.css = css
+	}
+	return sel, nil
+}
+
+// nodes returns every node matching the selector below n.
+func (s *selector) nodes(n *html.Node) []*html.Node {
+	if s.css == nil {
+		return []*html.Node{n}
+	}
+	return s.css.MatchAll(n)
+}
+
+// value returns the value of the first node matching the selector below n.
+func (s *selector) value(n *html.Node) string {
+	if s == nil {
+		return ""
+	}
+
+	ns := s.nodes(n)
+	if len(ns) == 0 {
+		return ""
+	}
+	if s.attr != "" {
+		return attr(ns[0], s.attr)
+	}
+	return strings.TrimSpace(text(ns[0]))
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+// text returns the concatenated text content of n, as it would be rendered.
+func text(n *html.Node) string {
+	var b strings.Builder
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+		case html.ElementNode:
+			if n.Data == "br" {
+				b.WriteString("\n")
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return b.String()
+}
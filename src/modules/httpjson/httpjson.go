@@ -0,0 +1,285 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package httpjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/stopper"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"text/template"
+	"time"
+)
+
+// httpjson is a declarative input, which lists documents from any JSON API
+// returning an array of items, and scrapes them from a templated URL.
+type httpjson struct {
+	Interval time.Duration
+	Timeout  time.Duration
+
+	// Headers are sent with every request, after environment variables expansion.
+	Headers  map[string]string
+	Username string
+	Password string
+
+	Listing  listingConfig
+	Fields   fieldsConfig
+	Scraping scrapeConfig `yaml:"scrape"`
+
+	scrapeURL *template.Template
+}
+
+type listingConfig struct {
+	URL string
+	// Items is the path to the array of items in the listing response.
+	Items string
+}
+
+// fieldsConfig maps paths, relative to each listed item, onto the fields of a
+// vautour.Document.
+type fieldsConfig struct {
+	ID        string
+	Title     string
+	User      string
+	CreatedAt string
+	Size      string
+	URL       string
+	// Tags is the path to either an array of tags or a single tag.
+	Tags string
+	// Metadata maps metadata keys onto paths, whose values are kept as is.
+	Metadata map[string]string
+
+	// TimeFormat is the layout of CreatedAt, when not a UNIX timestamp.
+	TimeFormat string
+}
+
+type scrapeConfig struct {
+	// URL is a text/template executed against the listed document (e.g.
+	// `https://example.com/raw/{{.ID}}`). The document's URL is used if empty.
+	URL string
+	// Content is the path to the content in the response, if it is JSON.
+	// The whole response body is used if empty.
+	Content string
+}
+
+func init() {
+	modules.Register("httpjson", &httpjson{})
+}
+
+func (h *httpjson) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	h.Interval = 30 * time.Second
+	h.Timeout = 5 * time.Second
+	h.Fields.ID = "id"
+	h.Fields.TimeFormat = time.RFC3339
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, h); err != nil {
+		return err
+	}
+	if h.Listing.URL == "" && h.Interval > 0 {
+		return errors.New("missing listing url")
+	}
+	if h.Fields.ID == "" {
+		return errors.New("missing id field mapping")
+	}
+
+	if h.Scraping.URL != "" {
+		t, err := template.New("scrape").Parse(h.Scraping.URL)
+		if err != nil {
+			return fmt.Errorf("invalid scrape url template: %s", err)
+		}
+		h.scrapeURL = t
+	}
+
+	return nil
+}
+
+func (h *httpjson) List(st *stopper.Stopper, ch chan *vautour.Document) error {
+	if h.Interval <= 0 {
+		<-st.Chan()
+		return nil
+	}
+
+	t := time.NewTicker(h.Interval)
+	defer t.Stop()
+	for {
+		// Wait for next loop.
+		select {
+		case <-st.Chan():
+			return nil
+		case <-t.C:
+		}
+
+		// List.
+		ds, err := h.list()
+		if err != nil {
+			log.WithField("role", "lister").WithField("module", "httpjson").WithField("url", h.Listing.URL).WithError(err).Warn("failed to list new documents")
+			continue
+		}
+		for _, d := range ds {
+			ch <- d
+		}
+	}
+}
+
+func (h *httpjson) list() ([]*vautour.Document, error) {
+	body, err := h.get(h.Listing.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse listing: %s", err)
+	}
+	v, ok := lookup(v, h.Listing.Items)
+	if !ok {
+		return nil, fmt.Errorf("items %q not found in listing", h.Listing.Items)
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("items %q is not an array", h.Listing.Items)
+	}
+
+	var ds []*vautour.Document
+	for _, item := range items {
+		d, err := h.document(item)
+		if err != nil {
+			log.WithField("role", "lister").WithField("module", "httpjson").WithField("url", h.Listing.URL).WithError(err).Warn("failed to parse listed item")
+			continue
+		}
+		ds = append(ds, d)
+	}
+	return ds, nil
+}
+
+// document maps a listed item onto a vautour.Document, as per the configured
+// fields.
+func (h *httpjson) document(item interface{}) (*vautour.Document, error) {
+	field := func(path string) interface{} {
+		if path == "" {
+			return nil
+		}
+		v, _ := lookup(item, path)
+		return v
+	}
+
+	d := &vautour.Document{
+		ID:    toString(field(h.Fields.ID)),
+		Title: toString(field(h.Fields.Title)),
+		User:  toString(field(h.Fields.User)),
+		URL:   toString(field(h.Fields.URL)),
+	}
+	if d.ID == "" {
+		return nil, fmt.Errorf("missing id %q", h.Fields.ID)
+	}
+
+	var err error
+	if d.CreatedAt, err = toTime(field(h.Fields.CreatedAt), h.Fields.TimeFormat); err != nil {
+		return nil, fmt.Errorf("invalid %q: %s", h.Fields.CreatedAt, err)
+	}
+	if d.Size, err = toInt(field(h.Fields.Size)); err != nil {
+		return nil, fmt.Errorf("invalid %q: %s", h.Fields.Size, err)
+	}
+	for k, path := range h.Fields.Metadata {
+		if v := field(path); v != nil {
+			d.SetMetadata(k, v)
+		}
+	}
+	switch t := field(h.Fields.Tags).(type) {
+	case []interface{}:
+		for _, tag := range t {
+			d.AddTags(toString(tag))
+		}
+	case nil:
+	default:
+		d.AddTags(toString(t))
+	}
+
+	return d, nil
+}
+
+func (h *httpjson) Scrape(d *vautour.Document) error {
+	u := d.URL
+	if h.scrapeURL != nil {
+		var b bytes.Buffer
+		if err := h.scrapeURL.Execute(&b, d); err != nil {
+			return fmt.Errorf("failed to render scrape url: %s", err)
+		}
+		u = b.String()
+	}
+	if u == "" {
+		return errors.New("no url to scrape")
+	}
+
+	body, err := h.get(u)
+	if err != nil {
+		log.WithField("role", "scraper").WithField("module", "httpjson").WithField("item_id", d.ID).WithError(err).Warn("failed to scrape document")
+		return err
+	}
+
+	if h.Scraping.Content != "" {
+		v, err := decode(bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to parse scraped document: %s", err)
+		}
+		v, ok := lookup(v, h.Scraping.Content)
+		if !ok {
+			return fmt.Errorf("content %q not found in scraped document", h.Scraping.Content)
+		}
+		body = []byte(toString(v))
+	}
+
+	d.Content = body
+	if d.Size == 0 {
+		d.Size = len(d.Content)
+	}
+
+	return nil
+}
+
+func (h *httpjson) get(u string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range h.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	if h.Username != "" || h.Password != "" {
+		req.SetBasicAuth(os.ExpandEnv(h.Username), os.ExpandEnv(h.Password))
+	}
+
+	client := &http.Client{Timeout: h.Timeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
@@ -0,0 +1,186 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ioc
+
+import (
+	"golang.org/x/net/publicsuffix"
+	"net"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Defanged forms, e.g. hxxps://example[.]com or john[at]example(.)com.
+	refanger = strings.NewReplacer(
+		"hxxp", "http", "hXXp", "http", "HXXP", "HTTP",
+		"[.]", ".", "(.)", ".", "{.}", ".", "[dot]", ".", "(dot)", ".",
+		"[:]", ":", "[://]", "://",
+		"[@]", "@", "[at]", "@", "(at)", "@",
+	)
+
+	urlRegexp    = regexp.MustCompile(`(?i)\b(?:https?|ftp)://[^\s"'<>\x60\\]+`)
+	emailRegexp  = regexp.MustCompile(`(?i)\b[a-z0-9._%+\-]+@((?:[a-z0-9](?:[a-z0-9\-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9\-]{1,62})\b`)
+	domainRegexp = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9\-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9\-]{1,62}\b`)
+	onionRegexp  = regexp.MustCompile(`(?i)\b(?:[a-z2-7]{16}|[a-z2-7]{56})\.onion\b`)
+	ipv4Regexp   = regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`)
+	ipv6Regexp   = regexp.MustCompile(`(?i)(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}`)
+	hashRegexp   = regexp.MustCompile(`\b[A-Fa-f0-9]{32,64}\b`)
+	cveRegexp    = regexp.MustCompile(`(?i)\bCVE-\d{4}-\d{4,7}\b`)
+
+	// Country-code TLDs that are far more often file extensions in pastes,
+	// e.g. config.py, readme.md or index.sh.
+	fileExtensions = map[string]bool{
+		"ac": true, "am": true, "cc": true, "la": true, "md": true,
+		"mk": true, "ml": true, "pl": true, "pm": true, "ps": true,
+		"py": true, "rs": true, "sh": true, "so": true, "sv": true,
+		"tf": true,
+	}
+)
+
+// Indicators are the indicators of compromise found in a document, with one
+// deduplicated list per type.
+type Indicators struct {
+	IPv4    []string `json:",omitempty"`
+	IPv6    []string `json:",omitempty"`
+	Domains []string `json:",omitempty"`
+	URLs    []string `json:",omitempty"`
+	Emails  []string `json:",omitempty"`
+	MD5     []string `json:",omitempty"`
+	SHA1    []string `json:",omitempty"`
+	SHA256  []string `json:",omitempty"`
+	CVEs    []string `json:",omitempty"`
+	Onions  []string `json:",omitempty"`
+
+	// Counts holds the number of distinct indicators per type, including
+	// those left out of the lists above by the limit.
+	Counts map[string]int
+}
+
+// Refang undoes the usual defanging of indicators.
+func Refang(s string) string {
+	return refanger.Replace(s)
+}
+
+// Extract returns the indicators found in the content, keeping at most max
+// indicators per type (unlimited if <= 0).
+func Extract(content []byte, max int) *Indicators {
+	s := Refang(string(content))
+	i := &Indicators{}
+
+	add := func(dst *[]string, seen map[string]bool, v string) {
+		if v == "" || seen[v] {
+			return
+		}
+		seen[v] = true
+		if max <= 0 || len(*dst) < max {
+			*dst = append(*dst, v)
+		}
+	}
+	seen := func() map[string]bool { return make(map[string]bool) }
+
+	urls := seen()
+	for _, u := range urlRegexp.FindAllString(s, -1) {
+		add(&i.URLs, urls, strings.TrimRight(u, ".,;:!?)]}>"))
+	}
+
+	emails := seen()
+	for _, m := range emailRegexp.FindAllStringSubmatch(s, -1) {
+		if isDomain(m[1]) {
+			add(&i.Emails, emails, strings.ToLower(m[0]))
+		}
+	}
+
+	domains, onions := seen(), seen()
+	for _, d := range domainRegexp.FindAllString(s, -1) {
+		d = strings.ToLower(d)
+		if strings.HasSuffix(d, ".onion") {
+			continue
+		}
+		if isDomain(d) {
+			add(&i.Domains, domains, d)
+		}
+	}
+	for _, o := range onionRegexp.FindAllString(s, -1) {
+		add(&i.Onions, onions, strings.ToLower(o))
+	}
+
+	ipv4s, ipv6s := seen(), seen()
+	for _, ip := range ipv4Regexp.FindAllString(s, -1) {
+		add(&i.IPv4, ipv4s, ip)
+	}
+	for _, c := range ipv6Regexp.FindAllString(s, -1) {
+		if ip := net.ParseIP(c); ip != nil && ip.To4() == nil && strings.Count(c, ":") >= 2 && c != "::" {
+			add(&i.IPv6, ipv6s, ip.String())
+		}
+	}
+
+	md5s, sha1s, sha256s := seen(), seen(), seen()
+	for _, h := range hashRegexp.FindAllString(s, -1) {
+		h = strings.ToLower(h)
+		switch len(h) {
+		case 32:
+			add(&i.MD5, md5s, h)
+		case 40:
+			add(&i.SHA1, sha1s, h)
+		case 64:
+			add(&i.SHA256, sha256s, h)
+		}
+	}
+
+	cves := seen()
+	for _, c := range cveRegexp.FindAllString(s, -1) {
+		add(&i.CVEs, cves, strings.ToUpper(c))
+	}
+
+	i.Counts = map[string]int{
+		"IPv4":    len(ipv4s),
+		"IPv6":    len(ipv6s),
+		"Domains": len(domains),
+		"URLs":    len(urls),
+		"Emails":  len(emails),
+		"MD5":     len(md5s),
+		"SHA1":    len(sha1s),
+		"SHA256":  len(sha256s),
+		"CVEs":    len(cves),
+		"Onions":  len(onions),
+	}
+	return i
+}
+
+// Empty returns whether no indicator was found.
+func (i *Indicators) Empty() bool {
+	for _, n := range i.Counts {
+		if n > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isDomain returns whether d is a registrable domain (or a subdomain of one)
+// under an ICANN public suffix, which rules out file names such as `main.go`.
+// File names whose extension is also a country-code TLD (`config.py`) are
+// ruled out by the fileExtensions list.
+func isDomain(d string) bool {
+	d = strings.ToLower(d)
+	suffix, icann := publicsuffix.PublicSuffix(d)
+	if !icann || suffix == d || fileExtensions[suffix] {
+		return false
+	}
+	_, err := publicsuffix.EffectiveTLDPlusOne(d)
+	return err == nil
+}
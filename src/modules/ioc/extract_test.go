@@ -0,0 +1,42 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ioc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractDomains(t *testing.T) {
+	i := Extract([]byte("run ./index.sh, edit config.py & readme.md, or main.go; see evil[.]example.com or sub.domain.co.uk"), 0)
+	if want := []string{"evil.example.com", "sub.domain.co.uk"}; !reflect.DeepEqual(i.Domains, want) {
+		t.Errorf("expected %v, got %v", want, i.Domains)
+	}
+}
+
+func TestExtractCounts(t *testing.T) {
+	i := Extract([]byte("1.1.1.1 2.2.2.2 3.3.3.3 2.2.2.2 CVE-2019-0001"), 2)
+	if want := []string{"1.1.1.1", "2.2.2.2"}; !reflect.DeepEqual(i.IPv4, want) {
+		t.Errorf("expected %v, got %v", want, i.IPv4)
+	}
+	if i.Counts["IPv4"] != 3 || i.Counts["CVEs"] != 1 || i.Counts["Domains"] != 0 {
+		t.Errorf("unexpected counts %v", i.Counts)
+	}
+	if i.Empty() || !Extract([]byte("nothing to see"), 2).Empty() {
+		t.Error("unexpected Empty result")
+	}
+}
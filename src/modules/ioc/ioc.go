@@ -0,0 +1,85 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package ioc extracts indicators of compromise (IPs, domains, URLs, hashes,
+// ...) out of documents. Processors configured after it can read them back
+// with FromDocument.
+package ioc
+
+import (
+	"encoding/json"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+)
+
+// ModuleName is the name under which indicators are stored in Processed.
+const ModuleName = "ioc"
+
+type ioc struct {
+	// MaxPerType is the maximum number of indicators kept per type.
+	MaxPerType int
+}
+
+func init() {
+	modules.Register("ioc", &ioc{})
+}
+
+func (i *ioc) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	i.MaxPerType = 1000
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, i); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (i *ioc) Process(d *vautour.Document) error {
+	indicators := Extract(d.Content, i.MaxPerType)
+	if indicators.Empty() {
+		return nil
+	}
+
+	j, err := json.Marshal(indicators)
+	if err != nil {
+		return err
+	}
+	d.Processed = append(d.Processed, vautour.ProcessedData{
+		Module:     ModuleName,
+		RawMessage: j,
+	})
+	log.WithField("role", "processor").WithField("module", "ioc").WithField("item_id", d.ID).WithField("counts", indicators.Counts).Debug("extracted indicators")
+
+	return nil
+}
+
+// FromDocument returns the indicators extracted from the document by a
+// previous processor, if any.
+func FromDocument(d *vautour.Document) (*Indicators, bool) {
+	for _, p := range d.Processed {
+		if p.Module != ModuleName {
+			continue
+		}
+		var i Indicators
+		if err := json.Unmarshal(p.RawMessage, &i); err == nil {
+			return &i, true
+		}
+	}
+	return nil, false
+}
@@ -17,10 +17,13 @@
 package mailer
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/scoring"
 	"github.com/quentin-m/vautour/src/pkg/vautour"
+	"text/template"
 
 	mail "github.com/kataras/go-mailer"
 )
@@ -29,6 +32,29 @@ type mailer struct {
 	SMTP mail.Config
 	Recipients []string
 	MinScore int
+	// MinSeverity, if set, only lets through the documents of at least that
+	// severity (info, low, medium, high, critical).
+	MinSeverity string
+	// Tags, if set, only lets through the documents bearing at least one of them.
+	Tags []string
+	// SkipNearDuplicates, if set, does not send the documents clustered with
+	// an earlier one by the fuzzyhash processor.
+	SkipNearDuplicates bool
+
+	// Subject and Body are text/template executed against the document, the
+	// body defaults to the document's JSON.
+	Subject string
+	Body string
+
+	subject *template.Template
+	body *template.Template
+}
+
+var funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.MarshalIndent(v, "", "    ")
+		return string(b), err
+	},
 }
 
 func init() {
@@ -38,33 +64,67 @@ func init() {
 func (e *mailer) Configure(cfg *modules.ModuleConfig) error {
 	// Default configuration.
 	e.SMTP.UseCommand = true
+	e.Subject = "[Vautour] An item from {{.InputModuleName}} matched with score {{.Score}}"
+	e.Body = "{{json .}}"
 
 	// Parse parameters.
 	if err := modules.ParseParams(cfg.Params, e); err != nil {
 		return err
 	}
 
+	if e.MinSeverity != "" && scoring.Level(e.MinSeverity) < 0 {
+		return fmt.Errorf("unknown severity %q", e.MinSeverity)
+	}
+
+	var err error
+	if e.subject, err = template.New("subject").Funcs(funcs).Parse(e.Subject); err != nil {
+		return fmt.Errorf("invalid subject template: %s", err)
+	}
+	if e.body, err = template.New("body").Funcs(funcs).Parse(e.Body); err != nil {
+		return fmt.Errorf("invalid body template: %s", err)
+	}
+
 	return nil
 }
 
 func (e *mailer) Send(d *vautour.Document) error {
-	// Skip if the score is too low.
+	// Skip if the score or severity is too low, or if the document bears none
+	// of the tags.
 	if d.Score < e.MinScore {
 		return nil
 	}
+	if e.MinSeverity != "" && scoring.Level(d.Severity) < scoring.Level(e.MinSeverity) {
+		return nil
+	}
+	if len(e.Tags) > 0 && !hasAnyTag(d, e.Tags) {
+		return nil
+	}
+	if e.SkipNearDuplicates && d.ClusterID != "" && d.ClusterID != d.ID {
+		return nil
+	}
 
 	// Format the document.
-	dj, err := json.MarshalIndent(d, "", "    ")
-	if err != nil {
+	var subject, body bytes.Buffer
+	if err := e.subject.Execute(&subject, d); err != nil {
+		return err
+	}
+	if err := e.body.Execute(&body, d); err != nil {
 		return err
 	}
 
 	// Send the e-mail.
 	return mail.New(e.SMTP).Send(
-		fmt.Sprintf("[Vautour] An item from %s matched with score %d", d.InputModuleName, d.Score),
-		string(dj),
+		subject.String(),
+		body.String(),
 		e.Recipients...
 	)
+}
 
-	return nil
+func hasAnyTag(d *vautour.Document, tags []string) bool {
+	for _, t := range tags {
+		if d.HasTag(t) {
+			return true
+		}
+	}
+	return false
 }
@@ -49,6 +49,19 @@ func Register(name string, e interface{}) {
 	Modules[name] = e
 }
 
+// New returns a new, unconfigured, instance of the module registered under the
+// given driver name, so that a single driver can back several modules.
+func New(driver string) interface{} {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	e, ok := Modules[driver]
+	if !ok {
+		return nil
+	}
+	return reflect.New(reflect.TypeOf(e).Elem()).Interface()
+}
+
 func ParseParams(params map[string]interface{}, cfg interface{}) error {
 	yConfig, err := yaml.Marshal(params)
 	if err != nil {
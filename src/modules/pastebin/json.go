@@ -18,6 +18,7 @@ package pastebin
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/quentin-m/vautour/src/pkg/vautour"
 	"strconv"
 	"strings"
@@ -26,6 +27,24 @@ import (
 
 type pastes []*paste
 
+// UnmarshalJSON decodes every paste of the listing independently, skipping the
+// malformed ones rather than failing the whole listing.
+func (ps *pastes) UnmarshalJSON(b []byte) error {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(b, &raws); err != nil {
+		return err
+	}
+
+	for _, raw := range raws {
+		var p paste
+		if err := json.Unmarshal(raw, &p); err != nil {
+			continue
+		}
+		*ps = append(*ps, &p)
+	}
+	return nil
+}
+
 type paste struct {
 	vautour.Document
 }
@@ -36,24 +55,47 @@ func (p *paste) UnmarshalJSON(b []byte) (err error) {
 		return err
 	}
 
-	p.ID = v["key"].(string)
-	p.Title = v["title"].(string)
-	p.User = v["user"].(string)
-	p.URL = v["full_url"].(string)
+	// Any field may be missing or null, only the key is mandatory.
+	p.ID = toString(v["key"])
+	if p.ID == "" {
+		return errors.New("paste without key")
+	}
+	p.Title = toString(v["title"])
+	p.User = toString(v["user"])
+	p.URL = toString(v["full_url"])
+	if syntax := toString(v["syntax"]); syntax != "" {
+		p.SetMetadata("syntax", syntax)
+	}
 
-	if v, err := string2time(v["date"].(string)); err == nil {
+	if v, err := string2time(toString(v["date"])); err == nil {
 		p.CreatedAt = v
 	}
-	if v, err := string2int(v["size"].(string)); err == nil {
+	if v, err := string2int(toString(v["size"])); err == nil {
 		p.Size = v
 	}
-	if v, err := string2time(v["expire"].(string)); err == nil {
+	if v, err := string2time(toString(v["expire"])); err == nil {
 		p.ExpireAt = v
 	}
+	if v, err := string2int(toString(v["hits"])); err == nil {
+		p.SetMetadata("hits", v)
+	}
 
 	return
 }
 
+// toString returns the string representation of a JSON scalar, or an empty
+// string for null & other types.
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
 func string2time(s string) (time.Time, error) {
 	r := strings.Replace(s, `"`, ``, -1)
 
@@ -0,0 +1,50 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package pastebin
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPastesUnmarshalJSON(t *testing.T) {
+	listing := `[
+		{"key": "abc123", "title": "dump", "user": null, "full_url": "https://pastebin.com/abc123", "syntax": "sql", "date": "1546300800", "size": 42, "expire": "0", "hits": "7"},
+		{"title": "no key"},
+		{"key": "def456", "syntax": null, "hits": null}
+	]`
+
+	var ps pastes
+	if err := json.Unmarshal([]byte(listing), &ps); err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 2 {
+		t.Fatalf("expected 2 pastes, got %d", len(ps))
+	}
+
+	// The listed documents are the embedded ones, which must carry the metadata.
+	d := &ps[0].Document
+	if d.ID != "abc123" || d.Title != "dump" || d.Size != 42 || d.CreatedAt.Unix() != 1546300800 || !d.ExpireAt.IsZero() {
+		t.Errorf("unexpected document %+v", d)
+	}
+	if d.Metadata["syntax"] != "sql" || d.Metadata["hits"] != 7 {
+		t.Errorf("unexpected metadata %v", d.Metadata)
+	}
+	if d := &ps[1].Document; d.ID != "def456" || len(d.Metadata) != 0 {
+		t.Errorf("unexpected document %+v", d)
+	}
+}
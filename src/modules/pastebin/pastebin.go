@@ -17,6 +17,7 @@
 package pastebin
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
@@ -27,20 +28,28 @@ import (
 	"io/ioutil"
 	"net/http"
 	"regexp"
+	"strings"
 	"time"
 )
 
 const (
-	listingURL = "https://scrape.pastebin.com/api_scraping.php?limit=250"
-	scrapingURL = "https://scrape.pastebin.com/api_scrape_item.php?i=%s"
+	defaultURL = "https://scrape.pastebin.com"
+	listingPath = "/api_scraping.php?limit=%d"
+	scrapingPath = "/api_scrape_item.php?i=%s"
+
+	// The scraping API does not list more than 250 pastes at once.
+	maxLimit = 250
 )
 
 var (
 	noAcccessREx = regexp.MustCompile("YOUR IP: .* DOES NOT HAVE ACCESS.")
+	notFoundREx = regexp.MustCompile("(?i)^Error, we cannot find this paste")
 )
 
 type pastebin struct{
 	Interval time.Duration
+	URL string
+	Limit int
 }
 
 func init() {
@@ -49,7 +58,17 @@ func init() {
 
 func (p *pastebin) Configure(cfg *modules.ModuleConfig) error {
 	p.Interval = 15 * time.Second
-	return modules.ParseParams(cfg.Params, p)
+	p.URL = defaultURL
+	p.Limit = maxLimit
+
+	if err := modules.ParseParams(cfg.Params, p); err != nil {
+		return err
+	}
+	p.URL = strings.TrimSuffix(p.URL, "/")
+	if p.Limit <= 0 || p.Limit > maxLimit {
+		p.Limit = maxLimit
+	}
+	return nil
 }
 
 func (p *pastebin) List(st *stopper.Stopper, ch chan *vautour.Document) error {
@@ -69,47 +88,72 @@ func (p *pastebin) List(st *stopper.Stopper, ch chan *vautour.Document) error {
 
 		// Scrape.
 		client := &http.Client{Timeout: time.Second * 5}
-		res, err := client.Get(listingURL)
+		res, err := client.Get(p.URL + fmt.Sprintf(listingPath, p.Limit))
 		if err != nil {
 			log.WithField("role", "lister").WithField("module", "pastebin").WithError(err).Warn("failed to list new pastes")
 			continue
 		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			log.WithField("role", "lister").WithField("module", "pastebin").WithError(err).Warn("failed to list new pastes")
+			continue
+		}
+		if noAcccessREx.Match(body) {
+			log.WithField("role", "lister").WithField("module", "pastebin").WithError(vautour.ErrAccessDenied).Warn("failed to list new pastes")
+			continue
+		}
 
 		// Decode & Add.
 		var ps pastes
-		if err := json.NewDecoder(res.Body).Decode(&ps); err != nil {
-			res.Body.Close()
+		if err := json.NewDecoder(bytes.NewReader(body)).Decode(&ps); err != nil {
 			log.WithField("role", "lister").WithField("module", "pastebin").WithError(err).Warn("failed to parse new list of pastes")
 			continue
 		}
 		for _, p := range ps {
 			ch <- &p.Document
 		}
-		res.Body.Close()
 	}
 }
 
+// Scrape fetches the content of the paste. Errors are typed, so that deleted
+// pastes get dropped while other failures are retried.
 func (p *pastebin) Scrape(d *vautour.Document) error {
 	client := &http.Client{Timeout: time.Second * 5}
-	res, err := client.Get(fmt.Sprintf(scrapingURL, d.ID))
+	res, err := client.Get(p.URL + fmt.Sprintf(scrapingPath, d.ID))
 	if err != nil {
 		log.WithField("role", "scraper").WithField("module", "pastebin").WithField("item_id", d.ID).WithError(err).Warn("failed to scrape paste")
-		return err
+		return errors.Wrap(vautour.ErrTransient, err.Error())
 	}
 	defer res.Body.Close()
 
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return errors.Wrap(vautour.ErrNotFound, res.Status)
+	case res.StatusCode == http.StatusForbidden || res.StatusCode == http.StatusUnauthorized:
+		return errors.Wrap(vautour.ErrAccessDenied, res.Status)
+	case res.StatusCode != http.StatusOK:
+		return errors.Wrap(vautour.ErrTransient, res.Status)
+	}
+
 	d.Content, err = ioutil.ReadAll(res.Body)
 	if err != nil {
 		log.WithField("role", "scraper").WithField("module", "pastebin").WithField("item_id", d.ID).WithError(err).Warn("failed to scrape paste")
-		return err
+		return errors.Wrap(vautour.ErrTransient, err.Error())
 	}
 
 	// Sometimes, the scraping API will return unauthorized regardless of whether the IP is indeed authorized.
 	// However, retries will eventually succeed.
-	if err := errors.New(noAcccessREx.FindString(string(d.Content))); err.Error() != "" {
-		log.WithField("role", "scraper").WithField("module", "pastebin").WithField("item_id", d.ID).WithError(err).Warn("failed to scrape paste")
-		return err
+	if m := noAcccessREx.Find(d.Content); m != nil {
+		d.Content = nil
+		return errors.Wrap(vautour.ErrAccessDenied, string(m))
+	}
+
+	// Pastes may be deleted, or expire, between listing & scraping.
+	if len(d.Content) == 0 || notFoundREx.Match(d.Content) {
+		d.Content = nil
+		return vautour.ErrNotFound
 	}
 
 	return nil
-}
\ No newline at end of file
+}
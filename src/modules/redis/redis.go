@@ -156,3 +156,49 @@ func (q *redis) Bookkeep(queues []string) {
 		}
 	}
 }
+
+func (q *redis) AddToIndex(index string, keys []string, value string, ttl time.Duration) error {
+	// Index entries are sorted by expiration, outdated ones are pruned on the way.
+	expireAt := time.Now().Add(ttl)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+
+	p := q.c.Pipeline()
+	for _, key := range keys {
+		p.ZAdd(index + ":" + key, lib.Z{Member: value, Score: float64(expireAt.Unix())})
+		p.ZRemRangeByScore(index + ":" + key, "-inf", now)
+		p.ExpireAt(index + ":" + key, expireAt)
+	}
+	if _, err := p.Exec(); err != nil {
+		return fmt.Errorf("(AddToIndex) %s", err)
+	}
+	return nil
+}
+
+func (q *redis) LookupIndex(index string, keys []string, max int) ([]string, error) {
+	// A zero count means no limit.
+	if max < 0 {
+		max = 0
+	}
+	opt := lib.ZRangeBy{Min: strconv.FormatInt(time.Now().Unix(), 10), Max: "+inf", Count: int64(max)}
+
+	p := q.c.Pipeline()
+	cmds := make([]*lib.StringSliceCmd, 0, len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, p.ZRevRangeByScore(index + ":" + key, opt))
+	}
+	if _, err := p.Exec(); err != nil && err != lib.Nil {
+		return nil, fmt.Errorf("(LookupIndex) %s", err)
+	}
+
+	var values []string
+	seen := make(map[string]bool)
+	for _, cmd := range cmds {
+		for _, v := range cmd.Val() {
+			if !seen[v] {
+				seen[v] = true
+				values = append(values, v)
+			}
+		}
+	}
+	return values, nil
+}
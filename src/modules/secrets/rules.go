@@ -0,0 +1,215 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package secrets
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"regexp"
+	"strings"
+)
+
+// ruleFile is the format of gitleaks rule files.
+// https://github.com/gitleaks/gitleaks#configuration
+type ruleFile struct {
+	Title      string
+	Rules      []ruleConfig      `toml:"rules"`
+	Allowlist  *allowlistConfig  `toml:"allowlist"`
+	Allowlists []allowlistConfig `toml:"allowlists"`
+}
+
+type ruleConfig struct {
+	ID          string            `toml:"id"`
+	Description string            `toml:"description"`
+	Regex       string            `toml:"regex"`
+	SecretGroup int               `toml:"secretGroup"`
+	Entropy     number            `toml:"entropy"`
+	Keywords    []string          `toml:"keywords"`
+	Tags        []string          `toml:"tags"`
+	Allowlist   *allowlistConfig  `toml:"allowlist"`
+	Allowlists  []allowlistConfig `toml:"allowlists"`
+
+	// Score is specific to Vautour, and ignored by gitleaks.
+	Score *int `toml:"score"`
+}
+
+// number is a float that may be written as an integer (e.g. `entropy = 3`).
+type number float64
+
+func (n *number) UnmarshalTOML(v interface{}) error {
+	switch t := v.(type) {
+	case int64:
+		*n = number(t)
+	case float64:
+		*n = number(t)
+	default:
+		return fmt.Errorf("expected a number, got %T", v)
+	}
+	return nil
+}
+
+type allowlistConfig struct {
+	Description string   `toml:"description"`
+	Condition   string   `toml:"condition"`
+	Regexes     []string `toml:"regexes"`
+	RegexTarget string   `toml:"regexTarget"`
+	StopWords   []string `toml:"stopwords"`
+}
+
+type rule struct {
+	id          string
+	description string
+	regex       *regexp.Regexp
+	secretGroup int
+	entropy     float64
+	keywords    []string
+	tags        []string
+	allowlists  []*allowlist
+	score       int
+}
+
+type allowlist struct {
+	and       bool
+	regexes   []*regexp.Regexp
+	target    string
+	stopWords []string
+}
+
+// loadRules parses a gitleaks rule file. Rules without regex (e.g. matching
+// on file paths only) are skipped, as documents have no path.
+func loadRules(path string, defaultScore int) ([]*rule, error) {
+	var f ruleFile
+	if _, err := toml.DecodeFile(path, &f); err != nil {
+		return nil, err
+	}
+
+	global, err := compileAllowlists(f.Allowlist, f.Allowlists)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []*rule
+	for _, rc := range f.Rules {
+		if rc.Regex == "" {
+			continue
+		}
+
+		r := &rule{
+			id:          rc.ID,
+			description: rc.Description,
+			secretGroup: rc.SecretGroup,
+			entropy:     float64(rc.Entropy),
+			tags:        rc.Tags,
+			score:       defaultScore,
+		}
+		if rc.Score != nil {
+			r.score = *rc.Score
+		}
+		for _, k := range rc.Keywords {
+			r.keywords = append(r.keywords, strings.ToLower(k))
+		}
+
+		if r.regex, err = regexp.Compile(rc.Regex); err != nil {
+			return nil, fmt.Errorf("invalid regex of rule %s: %s", rc.ID, err)
+		}
+		if r.secretGroup > r.regex.NumSubexp() {
+			return nil, fmt.Errorf("invalid secretGroup of rule %s: %d, regex has %d group(s)", rc.ID, r.secretGroup, r.regex.NumSubexp())
+		}
+		if r.allowlists, err = compileAllowlists(rc.Allowlist, rc.Allowlists); err != nil {
+			return nil, fmt.Errorf("invalid allowlist of rule %s: %s", rc.ID, err)
+		}
+		r.allowlists = append(r.allowlists, global...)
+
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func compileAllowlists(single *allowlistConfig, multiple []allowlistConfig) ([]*allowlist, error) {
+	if single != nil {
+		multiple = append([]allowlistConfig{*single}, multiple...)
+	}
+
+	var als []*allowlist
+	for _, ac := range multiple {
+		al := &allowlist{
+			and:       strings.EqualFold(ac.Condition, "AND"),
+			target:    strings.ToLower(ac.RegexTarget),
+			stopWords: ac.StopWords,
+		}
+		if al.target == "" {
+			al.target = "secret"
+		}
+		for _, s := range ac.Regexes {
+			re, err := regexp.Compile(s)
+			if err != nil {
+				return nil, err
+			}
+			al.regexes = append(al.regexes, re)
+		}
+		als = append(als, al)
+	}
+	return als, nil
+}
+
+// allowed returns whether the finding is allowed by the allowlist. With the
+// AND condition, both the regexes and the stop words must match, when set.
+func (al *allowlist) allowed(secret, match, line string) bool {
+	target := secret
+	switch al.target {
+	case "match":
+		target = match
+	case "line":
+		target = line
+	}
+
+	var checks []bool
+	if len(al.regexes) > 0 {
+		ok := false
+		for _, re := range al.regexes {
+			if re.MatchString(target) {
+				ok = true
+				break
+			}
+		}
+		checks = append(checks, ok)
+	}
+	if len(al.stopWords) > 0 {
+		ok := false
+		lower := strings.ToLower(secret)
+		for _, w := range al.stopWords {
+			if strings.Contains(lower, strings.ToLower(w)) {
+				ok = true
+				break
+			}
+		}
+		checks = append(checks, ok)
+	}
+
+	if len(checks) == 0 {
+		return false
+	}
+	for _, ok := range checks {
+		if ok && !al.and {
+			return true
+		}
+		if !ok && al.and {
+			return false
+		}
+	}
+	return al.and
+}
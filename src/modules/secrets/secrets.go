@@ -0,0 +1,183 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/secret"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+)
+
+// secrets is a pure-Go alternative to YARA for secret detection, which loads
+// gitleaks rule files and scans documents with RE2.
+type secrets struct {
+	Paths []string
+	// Score is the score of the rules that do not define one.
+	Score int
+	// MaxFindings is the maximum number of findings per rule & per document.
+	MaxFindings int
+
+	rules []*rule
+}
+
+// finding is the ProcessedData of a secret found in a document.
+type finding struct {
+	Rule        string
+	Description string `json:",omitempty"`
+	Line        int
+	Secret      string
+	Entropy     float64  `json:",omitempty"`
+	Tags        []string `json:",omitempty"`
+
+	// start & end are the offsets of the secret in the content.
+	start, end int
+}
+
+func init() {
+	modules.Register("secrets", &secrets{})
+}
+
+func (s *secrets) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	s.Score = 5
+	s.MaxFindings = 10
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, s); err != nil {
+		return err
+	}
+	if len(s.Paths) == 0 {
+		return errors.New("no rule file configured")
+	}
+
+	for _, p := range s.Paths {
+		rules, err := loadRules(p, s.Score)
+		if err != nil {
+			return fmt.Errorf("could not load rules file %s: %s", p, err)
+		}
+		for _, r := range rules {
+			log.WithField("role", "processor").WithField("module", "secrets").Debugf("loaded rule %s", r.id)
+		}
+		s.rules = append(s.rules, rules...)
+	}
+
+	return nil
+}
+
+func (s *secrets) Process(d *vautour.Document) error {
+	// Keywords are matched case-insensitively, as gitleaks does.
+	lower := bytes.ToLower(d.Content)
+
+	for _, r := range s.rules {
+		if !hasKeyword(lower, r.keywords) {
+			continue
+		}
+
+		n := 0
+		for _, m := range r.regex.FindAllSubmatchIndex(d.Content, -1) {
+			if s.MaxFindings > 0 && n >= s.MaxFindings {
+				break
+			}
+
+			f, ok := r.check(d.Content, m)
+			if !ok {
+				continue
+			}
+			n++
+
+			j, err := json.Marshal(f)
+			if err != nil {
+				log.WithField("role", "processor").WithField("module", "secrets").WithField("item_id", d.ID).WithField("rule", r.id).Warn("failed to marshal finding")
+				continue
+			}
+			d.Processed = append(d.Processed, vautour.ProcessedData{
+				Module:     "secrets",
+				Score:      r.score,
+				Spans:      []vautour.Span{{Start: f.start, End: f.end}},
+				RawMessage: j,
+			})
+			d.MarkSensitive(f.start, f.end)
+			d.AddTags(r.tags...)
+			log.WithField("role", "processor").WithField("module", "secrets").WithField("item_id", d.ID).WithField("rule", r.id).Debug("matched rule")
+		}
+	}
+
+	return nil
+}
+
+// check extracts the secret out of a match, and verifies it against the
+// entropy threshold & allowlists of the rule.
+func (r *rule) check(content []byte, m []int) (*finding, bool) {
+	// The secret is the configured group, the only group, or the whole match.
+	g := r.secretGroup
+	if g == 0 && r.regex.NumSubexp() == 1 {
+		g = 1
+	}
+	if m[2*g] < 0 {
+		return nil, false
+	}
+	value := string(content[m[2*g]:m[2*g+1]])
+	match := string(content[m[0]:m[1]])
+
+	entropy := secret.Entropy(value)
+	if r.entropy > 0 && entropy < r.entropy {
+		return nil, false
+	}
+
+	start := bytes.LastIndexByte(content[:m[0]], '\n') + 1
+	end := bytes.IndexByte(content[m[1]:], '\n')
+	if end < 0 {
+		end = len(content)
+	} else {
+		end += m[1]
+	}
+	line := string(content[start:end])
+
+	for _, al := range r.allowlists {
+		if al.allowed(value, match, line) {
+			return nil, false
+		}
+	}
+
+	return &finding{
+		Rule:        r.id,
+		Description: r.description,
+		Line:        bytes.Count(content[:m[0]], []byte("\n")) + 1,
+		Secret:      secret.Redact(value),
+		Entropy:     entropy,
+		Tags:        r.tags,
+		start:       m[2*g],
+		end:         m[2*g+1],
+	}, true
+}
+
+func hasKeyword(lower []byte, keywords []string) bool {
+	if len(keywords) == 0 {
+		return true
+	}
+	for _, k := range keywords {
+		if bytes.Contains(lower, []byte(k)) {
+			return true
+		}
+	}
+	return false
+}
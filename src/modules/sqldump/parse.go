@@ -0,0 +1,553 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sqldump
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// sampleRows is the number of rows which values are used to classify the
+// columns.
+const sampleRows = 20
+
+var (
+	statement     = regexp.MustCompile(`(?i)\b(?:(CREATE\s+(?:TEMP(?:ORARY)?\s+)?TABLE(?:\s+IF\s+NOT\s+EXISTS)?)|(INSERT\s+(?:IGNORE\s+)?INTO|REPLACE\s+INTO)|(COPY))\s+`)
+	values        = regexp.MustCompile(`(?i)^\s*VALUES\s*`)
+	fromStdin     = regexp.MustCompile(`(?i)^\s*FROM\s+stdin\s*;[^\n]*\n`)
+	autoIncrement = regexp.MustCompile(`(?i)^[^;(]*?AUTO_INCREMENT\s*=\s*(\d+)`)
+	setval        = regexp.MustCompile(`(?i)setval\(\s*'(?:[^'.]*\.)?"?([^'"]+)"?'\s*,\s*(\d+)`)
+
+	// constraints are the first words of the table definitions that are not
+	// columns.
+	constraints = map[string]bool{
+		"PRIMARY": true, "KEY": true, "UNIQUE": true, "INDEX": true, "CONSTRAINT": true, "FOREIGN": true,
+		"CHECK": true, "FULLTEXT": true, "SPATIAL": true, "EXCLUDE": true,
+	}
+
+	// dialects are the markers of the SQL dialects.
+	dialects = []struct {
+		name    string
+		markers []string
+	}{
+		{"mysql", []string{"-- mysql dump", "engine=", "lock tables", "auto_increment", "/*!40", "`"}},
+		{"postgresql", []string{"-- postgresql database dump", "from stdin;", "pg_catalog", "owner to", "serial", "::"}},
+		{"sqlite", []string{"pragma ", "sqlite_sequence", "autoincrement", "begin transaction;"}},
+	}
+
+	// columnNames are the names of the sensitive columns, by type.
+	columnNames = []struct {
+		name string
+		re   *regexp.Regexp
+	}{
+		{"password", regexp.MustCompile(`(?i)^(?:user_?)?(?:pass(?:word|wd)?|pwd|pw)(?:_?hash(?:ed)?)?$|^(?:en)?crypted_?password$|^hash(?:ed)?_?pass(?:word)?$`)},
+		{"email", regexp.MustCompile(`(?i)(?:^|_)e_?mail(?:_?address)?$|^mail$`)},
+		{"phone", regexp.MustCompile(`(?i)(?:^|_)(?:phone|mobile|tel|telephone|msisdn|cell(?:phone)?)(?:_?(?:number|num|no))?$`)},
+		{"ip", regexp.MustCompile(`(?i)(?:^|_)ip(?:_?addr(?:ess)?)?$`)},
+		{"birthdate", regexp.MustCompile(`(?i)^(?:dob|birth_?date|date_?of_?birth|birthday)$`)},
+		{"payment-card", regexp.MustCompile(`(?i)^(?:cc|card|credit_?card)_?num(?:ber)?$|^credit_?card$`)},
+	}
+
+	// columnValues are the values of the sensitive columns, by type.
+	columnValues = []struct {
+		name string
+		re   *regexp.Regexp
+	}{
+		{"password", regexp.MustCompile(`^\$(?:2[abxy]?\$\d{2}\$[./A-Za-z0-9]{53}|argon2(?:i|d|id)\$|(?:1|5|6|apr1)\$[^$]+\$[./A-Za-z0-9]+|[PH]\$[./A-Za-z0-9]{31})$`)},
+		{"email", regexp.MustCompile(`^[^@\s]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}$`)},
+		{"phone", regexp.MustCompile(`^\+\d[\d ().\-]{6,18}\d$`)},
+		{"ip", regexp.MustCompile(`^(?:\d{1,3}\.){3}\d{1,3}$`)},
+	}
+
+	// hashTypes are the types of the values of the password columns, from
+	// the most specific.
+	hashTypes = []struct {
+		name string
+		re   *regexp.Regexp
+	}{
+		{"bcrypt", regexp.MustCompile(`^\$2[abxy]?\$\d{2}\$[./A-Za-z0-9]{53}$`)},
+		{"argon2", regexp.MustCompile(`^\$argon2(?:i|d|id)\$`)},
+		{"crypt", regexp.MustCompile(`^\$(?:1|5|6|apr1)\$[^$]+\$[./A-Za-z0-9]+$`)},
+		{"phpass", regexp.MustCompile(`^\$[PH]\$[./A-Za-z0-9]{31}$`)},
+		{"md5", regexp.MustCompile(`^[A-Fa-f0-9]{32}$`)},
+		{"sha1", regexp.MustCompile(`^[A-Fa-f0-9]{40}$`)},
+		{"sha256", regexp.MustCompile(`^[A-Fa-f0-9]{64}$`)},
+		{"sha512", regexp.MustCompile(`^[A-Fa-f0-9]{128}$`)},
+	}
+)
+
+// dump is the result of the parsing of SQL statements.
+type dump struct {
+	dialect string
+	tables  []*table
+	byName  map[string]*table
+	// estimates are the row counts given by sequences, by table or sequence
+	// name.
+	estimates map[string]int
+	hashTypes map[string]int
+	// passwords are the offsets of the password values in the content.
+	passwords [][2]int
+}
+
+type table struct {
+	name          string
+	columns       []*column
+	rows          int
+	autoIncrement int
+}
+
+type column struct {
+	name      string
+	sensitive string
+	samples   int
+	matches   map[string]int
+}
+
+// parse extracts the tables of the CREATE TABLE, INSERT & COPY statements of
+// b, in the MySQL, PostgreSQL & SQLite dialects.
+func parse(b []byte) *dump {
+	d := &dump{
+		dialect:   dialect(b),
+		byName:    make(map[string]*table),
+		estimates: make(map[string]int),
+		hashTypes: make(map[string]int),
+	}
+
+	for pos := 0; pos < len(b); {
+		m := statement.FindSubmatchIndex(b[pos:])
+		if m == nil {
+			break
+		}
+		for i := range m {
+			if m[i] >= 0 {
+				m[i] += pos
+			}
+		}
+
+		name, i := readIdent(b, m[1])
+		switch {
+		case name == "":
+			pos = m[1]
+		case m[2] >= 0:
+			pos = d.create(b, name, i)
+		case m[4] >= 0:
+			pos = d.insert(b, name, i)
+		default:
+			pos = d.copy(b, name, i)
+		}
+	}
+
+	for _, m := range setval.FindAllSubmatch(b, -1) {
+		if n, err := strconv.Atoi(string(m[2])); err == nil {
+			d.estimates[string(m[1])] = n
+		}
+	}
+	return d
+}
+
+// dialect returns the dialect which markers are the most frequent in b.
+func dialect(b []byte) string {
+	lower := bytes.ToLower(b)
+	best, max := "", 0
+	for _, d := range dialects {
+		n := 0
+		for _, m := range d.markers {
+			n += bytes.Count(lower, []byte(m))
+		}
+		if n > max {
+			best, max = d.name, n
+		}
+	}
+	return best
+}
+
+func (d *dump) table(name string) *table {
+	key := strings.ToLower(name)
+	t, ok := d.byName[key]
+	if !ok {
+		t = &table{name: name}
+		d.byName[key] = t
+		d.tables = append(d.tables, t)
+	}
+	return t
+}
+
+// create parses the column definitions of a CREATE TABLE statement, starting
+// at i, and returns the offset of its end.
+func (d *dump) create(b []byte, name string, i int) int {
+	i = skipSpace(b, i)
+	if i >= len(b) || b[i] != '(' {
+		return i
+	}
+	end := matchParen(b, i)
+	if end < 0 {
+		end = len(b)
+	}
+
+	t := d.table(name)
+	for _, def := range splitTop(b, i+1, end) {
+		fields := bytes.Fields(b[def[0]:def[1]])
+		if len(fields) == 0 {
+			continue
+		}
+		if constraints[strings.ToUpper(string(fields[0]))] {
+			continue
+		}
+		if col, _ := readIdent(b, def[0]); col != "" {
+			t.column(col)
+		}
+	}
+
+	if end < len(b) {
+		if m := autoIncrement.FindSubmatch(b[end:]); m != nil {
+			t.autoIncrement, _ = strconv.Atoi(string(m[1]))
+		}
+		end++
+	}
+	return end
+}
+
+// insert parses the rows of an INSERT statement, starting at i, and returns
+// the offset of its end.
+func (d *dump) insert(b []byte, name string, i int) int {
+	i = skipSpace(b, i)
+	var names []string
+	if i < len(b) && b[i] == '(' {
+		end := matchParen(b, i)
+		if end < 0 {
+			return len(b)
+		}
+		for _, c := range splitTop(b, i+1, end) {
+			col, _ := readIdent(b, c[0])
+			names = append(names, col)
+		}
+		i = end + 1
+	}
+	m := values.FindIndex(b[i:])
+	if m == nil {
+		// INSERT ... SELECT, or truncated statement.
+		return i
+	}
+	i += m[1]
+
+	sequence := strings.EqualFold(name, "sqlite_sequence")
+	var t *table
+	if !sequence {
+		t = d.table(name)
+	}
+	for {
+		i = skipSpace(b, i)
+		if i >= len(b) || b[i] != '(' {
+			return i
+		}
+		end := matchParen(b, i)
+		if end < 0 {
+			// Truncated row.
+			return len(b)
+		}
+		row := splitTop(b, i+1, end)
+		if sequence {
+			if len(row) == 2 {
+				if n, err := strconv.Atoi(unquote(b[row[1][0]:row[1][1]])); err == nil {
+					d.estimates[unquote(b[row[0][0]:row[0][1]])] = n
+				}
+			}
+		} else {
+			d.row(b, t, names, row, true)
+		}
+
+		i = skipSpace(b, end+1)
+		if i >= len(b) || b[i] != ',' {
+			return i
+		}
+		i++
+	}
+}
+
+// copy parses the rows of a PostgreSQL COPY ... FROM stdin statement,
+// starting at i, and returns the offset of its end.
+func (d *dump) copy(b []byte, name string, i int) int {
+	i = skipSpace(b, i)
+	var names []string
+	if i < len(b) && b[i] == '(' {
+		end := matchParen(b, i)
+		if end < 0 {
+			return len(b)
+		}
+		for _, c := range splitTop(b, i+1, end) {
+			col, _ := readIdent(b, c[0])
+			names = append(names, col)
+		}
+		i = end + 1
+	}
+	m := fromStdin.FindIndex(b[i:])
+	if m == nil {
+		return i
+	}
+	i += m[1]
+
+	t := d.table(name)
+	for i < len(b) {
+		end := bytes.IndexByte(b[i:], '\n')
+		if end < 0 {
+			end = len(b)
+		} else {
+			end += i
+		}
+		line := bytes.TrimRight(b[i:end], "\r")
+		if string(line) == `\.` {
+			return end
+		}
+
+		var row [][2]int
+		for start, j := i, i; j <= i+len(line); j++ {
+			if j == i+len(line) || b[j] == '\t' {
+				row = append(row, [2]int{start, j})
+				start = j + 1
+			}
+		}
+		d.row(b, t, names, row, false)
+		i = end + 1
+	}
+	return len(b)
+}
+
+// row records a row of t, which values are given by their offsets in b.
+func (d *dump) row(b []byte, t *table, names []string, row [][2]int, quoted bool) {
+	t.rows++
+	for k, v := range row {
+		var c *column
+		switch {
+		case k < len(names):
+			c = t.column(names[k])
+		case len(names) > 0:
+			continue
+		default:
+			for len(t.columns) <= k {
+				t.columns = append(t.columns, &column{matches: make(map[string]int)})
+			}
+			c = t.columns[k]
+		}
+
+		raw := b[v[0]:v[1]]
+		if quoted && len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'' {
+			v = [2]int{v[0] + 1, v[1] - 1}
+		}
+		value := unquote(raw)
+		if !quoted && value == `\N` || quoted && strings.EqualFold(value, "NULL") || value == "" {
+			continue
+		}
+
+		if c.samples < sampleRows {
+			c.samples++
+			for _, cv := range columnValues {
+				if cv.re.MatchString(value) {
+					c.matches[cv.name]++
+				}
+			}
+		}
+		if c.sensitive == "password" || columnValues[0].re.MatchString(value) {
+			d.passwords = append(d.passwords, v)
+			d.hashTypes[hashType(value)]++
+		}
+	}
+}
+
+// column returns the column of t with the given name, adding it if needed.
+func (t *table) column(name string) *column {
+	for _, c := range t.columns {
+		if strings.EqualFold(c.name, name) {
+			return c
+		}
+	}
+	c := &column{name: name, matches: make(map[string]int)}
+	for _, cn := range columnNames {
+		if cn.re.MatchString(name) {
+			c.sensitive = cn.name
+			break
+		}
+	}
+	t.columns = append(t.columns, c)
+	return c
+}
+
+// sensitiveType returns the sensitive type of the column, given by its name
+// or by most of its sampled values.
+func (c *column) sensitiveType() string {
+	if c.sensitive != "" {
+		return c.sensitive
+	}
+	for _, cv := range columnValues {
+		if c.samples > 0 && 2*c.matches[cv.name] > c.samples {
+			return cv.name
+		}
+	}
+	return ""
+}
+
+func hashType(v string) string {
+	for _, t := range hashTypes {
+		if t.re.MatchString(v) {
+			return t.name
+		}
+	}
+	return "plain"
+}
+
+// readIdent reads a possibly quoted & qualified identifier at i, and returns
+// its last part & the offset of its end.
+func readIdent(b []byte, i int) (string, int) {
+	i = skipSpace(b, i)
+	var name string
+	for i < len(b) {
+		switch b[i] {
+		case '`', '"', '[':
+			closing := b[i]
+			if closing == '[' {
+				closing = ']'
+			}
+			end := bytes.IndexByte(b[i+1:], closing)
+			if end < 0 {
+				return "", len(b)
+			}
+			name = string(b[i+1 : i+1+end])
+			i += end + 2
+		default:
+			j := i
+			for j < len(b) && isIdentByte(b[j]) {
+				j++
+			}
+			if j == i {
+				return name, i
+			}
+			name = string(b[i:j])
+			i = j
+		}
+		if i >= len(b) || b[i] != '.' {
+			break
+		}
+		i++
+	}
+	return name, i
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func skipSpace(b []byte, i int) int {
+	for i < len(b) && isSpaceByte(b[i]) {
+		i++
+	}
+	return i
+}
+
+// skipQuoted returns the offset following the string literal or quoted
+// identifier at i, or -1 if it is not terminated.
+func skipQuoted(b []byte, i int) int {
+	q := b[i]
+	for j := i + 1; j < len(b); j++ {
+		switch {
+		case b[j] == '\\' && q == '\'':
+			j++
+		case b[j] == q && j+1 < len(b) && b[j+1] == q:
+			j++
+		case b[j] == q:
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// matchParen returns the offset of the parenthesis closing the one at i, or
+// -1 if it is not closed.
+func matchParen(b []byte, i int) int {
+	depth := 0
+	for i < len(b) {
+		switch b[i] {
+		case '\'', '"', '`':
+			if i = skipQuoted(b, i); i < 0 {
+				return -1
+			}
+			continue
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+		i++
+	}
+	return -1
+}
+
+// splitTop splits b[start:end] at its top-level commas, and returns the
+// offsets of the trimmed parts.
+func splitTop(b []byte, start, end int) [][2]int {
+	var parts [][2]int
+	add := func(s, e int) {
+		for s < e && isSpaceByte(b[s]) {
+			s++
+		}
+		for e > s && isSpaceByte(b[e-1]) {
+			e--
+		}
+		if s < e {
+			parts = append(parts, [2]int{s, e})
+		}
+	}
+
+	depth, s := 0, start
+	for i := start; i < end; {
+		switch b[i] {
+		case '\'', '"', '`':
+			if i = skipQuoted(b, i); i < 0 || i > end {
+				i = end
+			}
+			continue
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				add(s, i)
+				s = i + 1
+			}
+		}
+		i++
+	}
+	add(s, end)
+	return parts
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// unquote returns the text of a SQL value.
+func unquote(v []byte) string {
+	s := string(v)
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	return strings.NewReplacer(`''`, `'`, `\'`, `'`, `\\`, `\`).Replace(s)
+}
@@ -0,0 +1,171 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sqldump
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+)
+
+var parseSeeds = []string{
+	"CREATE TABLE t (a int,\f);",
+	"CREATE TABLE t (a int,\v \u0085, b text);",
+	"CREATE TABLE `users` (\n  `id` int NOT NULL AUTO_INCREMENT,\n  `email` varchar(255),\n  `password` varchar(60),\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB AUTO_INCREMENT=1234;\n" +
+		"INSERT INTO `users` VALUES (1,'a@example.com','$2y$10$abcdefghijklmnopqrstuuABCDEFGHIJKLMNOPQRSTUVWXYZ01234'),(2,'b@example.com','x');\n",
+	"CREATE TABLE public.users (id integer, email text);\nCOPY public.users (id, email) FROM stdin;\n1\ta@example.com\n\\.\nSELECT pg_catalog.setval('public.users_id_seq', 42, true);\n",
+	"INSERT INTO t (a, b) VALUES ('it''s', 'x\\'y'), (",
+	"CREATE TABLE t (",
+	"COPY t FROM stdin;\n",
+}
+
+func TestParseWhitespaceDefinition(t *testing.T) {
+	d := parse([]byte("CREATE TABLE t (a int,\f);"))
+	if len(d.tables) != 1 || len(d.tables[0].columns) != 1 || d.tables[0].columns[0].name != "a" {
+		t.Fatalf("unexpected tables: %+v", d.tables)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	s := &sqldump{}
+	if err := s.Configure(&modules.ModuleConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		content   string
+		want      *summary
+		score     int
+		passwords int
+	}{
+		{
+			name: "mysql",
+			content: "-- MySQL dump 10.13\n" +
+				"CREATE TABLE `countries` (\n  `code` char(2) NOT NULL,\n  `name` varchar(64),\n  PRIMARY KEY (`code`)\n) ENGINE=InnoDB;\n" +
+				"CREATE TABLE `users` (\n  `id` int NOT NULL AUTO_INCREMENT,\n  `email` varchar(255),\n  `password` varchar(60),\n  `last_ip` varchar(45),\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB AUTO_INCREMENT=1235;\n" +
+				"INSERT INTO `users` VALUES (1,'a@example.com','$2y$10$abcdefghijklmnopqrstuuABCDEFGHIJKLMNOPQRSTUVWXYZ01234','10.0.0.1'),(2,'b@example.com','5f4dcc3b5aa765d61d8327deb882cf99','10.0.0.2');\n",
+			want: &summary{
+				Dialect: "mysql",
+				Tables: []tableSummary{
+					{Name: "users", Columns: []string{"id", "email", "password", "last_ip"}, Rows: 2, EstimatedRows: 1234, Sensitive: map[string]string{"email": "email", "password": "password", "last_ip": "ip"}},
+					{Name: "countries", Columns: []string{"code", "name"}},
+				},
+				Rows:          2,
+				EstimatedRows: 1234,
+				Sensitive:     []string{"email", "ip", "password"},
+				HashTypes:     map[string]int{"bcrypt": 1, "md5": 1},
+			},
+			// (5 + 5*3) * (1 + 0.5*3)
+			score:     50,
+			passwords: 2,
+		},
+		{
+			name: "postgresql copy",
+			content: "-- PostgreSQL database dump\n" +
+				"CREATE TABLE public.customers (\n    id integer NOT NULL,\n    email text,\n    phone text,\n    dob date\n);\n" +
+				"COPY public.customers (id, email, phone, dob) FROM stdin;\n" +
+				"1\tjane@example.org\t+33 6 12 34 56 78\t1990-01-01\n" +
+				"2\tjohn@example.org\t\\N\t1985-05-05\n" +
+				"\\.\n" +
+				"SELECT pg_catalog.setval('public.customers_id_seq', 50000, true);\n",
+			want: &summary{
+				Dialect: "postgresql",
+				Tables: []tableSummary{
+					{Name: "customers", Columns: []string{"id", "email", "phone", "dob"}, Rows: 2, EstimatedRows: 50000, Sensitive: map[string]string{"email": "email", "phone": "phone", "dob": "birthdate"}},
+				},
+				Rows:          2,
+				EstimatedRows: 50000,
+				Sensitive:     []string{"birthdate", "email", "phone"},
+			},
+			// (5 + 5*4) * (1 + 0.5*3), rounded
+			score: 63,
+		},
+		{
+			name: "sqlite",
+			content: "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\n" +
+				"CREATE TABLE accounts (id INTEGER PRIMARY KEY AUTOINCREMENT, login TEXT, pwd TEXT, card_number TEXT);\n" +
+				"INSERT INTO accounts VALUES(1,'alice','$1$salt$qJH7.N4xYta3aEG/dfqo/0','4111111111111111');\n" +
+				"INSERT INTO accounts VALUES(2,'bob','e38ad214943daad1d64c102faec29de4afe9da3d','5500000000000004');\n" +
+				"DELETE FROM sqlite_sequence;\nINSERT INTO sqlite_sequence VALUES('accounts',987);\nCOMMIT;\n",
+			want: &summary{
+				Dialect: "sqlite",
+				Tables: []tableSummary{
+					{Name: "accounts", Columns: []string{"id", "login", "pwd", "card_number"}, Rows: 2, EstimatedRows: 987, Sensitive: map[string]string{"pwd": "password", "card_number": "payment-card"}},
+				},
+				Rows:          2,
+				EstimatedRows: 987,
+				Sensitive:     []string{"password", "payment-card"},
+				HashTypes:     map[string]int{"crypt": 1, "sha1": 1},
+			},
+			// (5 + 5*2) * (1 + 0.5*2)
+			score:     30,
+			passwords: 2,
+		},
+		{
+			name:    "too small",
+			content: "CREATE TABLE t (id int, email text);\nINSERT INTO t VALUES (1,'a@example.com'),(2,'b@example.com');\n",
+		},
+	} {
+		d := &vautour.Document{Content: []byte(tc.content)}
+		if err := s.Process(d); err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		if tc.want == nil {
+			if len(d.Processed) != 0 {
+				t.Errorf("%s: unexpected results %+v", tc.name, d.Processed)
+			}
+			continue
+		}
+		if len(d.Processed) != 1 || !d.HasTag("sql-dump") || !d.HasTag("sql-dump-sensitive") {
+			t.Errorf("%s: expected a sensitive dump, got %+v (tags %v)", tc.name, d.Processed, d.Tags)
+			continue
+		}
+
+		var got summary
+		if err := json.Unmarshal(d.Processed[0].RawMessage, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(&got, tc.want) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.want, got)
+		}
+		if d.Processed[0].Score != tc.score {
+			t.Errorf("%s: expected a score of %d, got %d", tc.name, tc.score, d.Processed[0].Score)
+		}
+		if len(d.Sensitive) != tc.passwords {
+			t.Errorf("%s: expected %d sensitive spans, got %v", tc.name, tc.passwords, d.Sensitive)
+		}
+	}
+}
+
+func TestParseSeeds(t *testing.T) {
+	for _, s := range parseSeeds {
+		parse([]byte(s))
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, s := range parseSeeds {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		parse(b)
+	})
+}
@@ -0,0 +1,197 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sqldump
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"sort"
+	"strings"
+)
+
+// sqldump parses the SQL dump fragments (CREATE TABLE, INSERT & COPY
+// statements) of documents, and summarizes the scope of the breach: tables,
+// columns, estimated row counts & sensitive columns.
+type sqldump struct {
+	// MinRows is the minimum number of estimated rows for a document to be
+	// considered a dump.
+	MinRows int
+	// Score is the score of any dump, raised by SizeScore for every order of
+	// magnitude of its estimated rows, and multiplied by 1 + SensitiveFactor
+	// for every type of sensitive column (password, email, phone, ...) of its
+	// tables holding rows, up to MaxScore.
+	Score           int
+	SizeScore       int
+	SensitiveFactor float64
+	MaxScore        int
+	// MaxTables is the number of tables kept in the summary, the largest
+	// first.
+	MaxTables int
+}
+
+// summary is the ProcessedData of a dump. It never holds values.
+type summary struct {
+	Dialect       string `json:",omitempty"`
+	Tables        []tableSummary
+	Rows          int
+	EstimatedRows int
+	Sensitive     []string       `json:",omitempty"`
+	HashTypes     map[string]int `json:",omitempty"`
+}
+
+type tableSummary struct {
+	Name          string
+	Columns       []string `json:",omitempty"`
+	Rows          int
+	EstimatedRows int
+	// Sensitive are the types of the sensitive columns, by name, or by
+	// position (#1, #2, ...) for the columns which name is unknown.
+	Sensitive map[string]string `json:",omitempty"`
+}
+
+func init() {
+	modules.Register("sqldump", &sqldump{})
+}
+
+func (s *sqldump) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	s.MinRows = 5
+	s.Score = 5
+	s.SizeScore = 5
+	s.SensitiveFactor = 0.5
+	s.MaxScore = 100
+	s.MaxTables = 50
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, s); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sqldump) Process(d *vautour.Document) error {
+	dump := parse(d.Content)
+	sum := s.summarize(dump)
+	if sum.EstimatedRows == 0 || sum.EstimatedRows < s.MinRows {
+		return nil
+	}
+
+	score := int(math.Round(float64(s.Score+s.SizeScore*int(math.Log10(float64(sum.EstimatedRows)))) * (1 + s.SensitiveFactor*float64(len(sum.Sensitive)))))
+	if s.MaxScore > 0 && score > s.MaxScore {
+		score = s.MaxScore
+	}
+
+	j, err := json.Marshal(sum)
+	if err != nil {
+		return err
+	}
+	d.Processed = append(d.Processed, vautour.ProcessedData{
+		Module:     "sqldump",
+		Score:      score,
+		RawMessage: j,
+	})
+	d.AddTags("sql-dump")
+	if len(sum.Sensitive) > 0 {
+		d.AddTags("sql-dump-sensitive")
+	}
+	for _, p := range dump.passwords {
+		d.MarkSensitive(p[0], p[1])
+	}
+	log.WithField("role", "processor").WithField("module", "sqldump").WithField("item_id", d.ID).WithField("tables", len(dump.tables)).WithField("rows", sum.EstimatedRows).WithField("sensitive", strings.Join(sum.Sensitive, ",")).Debug("found SQL dump")
+
+	return nil
+}
+
+// summarize estimates the row counts of the tables of the dump, from their
+// rows & sequences, and lists their sensitive columns.
+func (s *sqldump) summarize(dump *dump) *summary {
+	sum := &summary{Dialect: dump.dialect}
+	if len(dump.hashTypes) > 0 {
+		sum.HashTypes = dump.hashTypes
+	}
+
+	sensitive := make(map[string]bool)
+	for _, t := range dump.tables {
+		ts := tableSummary{Name: t.name, Rows: t.rows, EstimatedRows: t.rows}
+		if t.autoIncrement-1 > ts.EstimatedRows {
+			ts.EstimatedRows = t.autoIncrement - 1
+		}
+		if n := sequenceEstimate(dump, t); n > ts.EstimatedRows {
+			ts.EstimatedRows = n
+		}
+
+		for k, c := range t.columns {
+			key := c.name
+			if key == "" {
+				key = fmt.Sprintf("#%d", k+1)
+			} else {
+				ts.Columns = append(ts.Columns, c.name)
+			}
+			if st := c.sensitiveType(); st != "" {
+				if ts.Sensitive == nil {
+					ts.Sensitive = make(map[string]string)
+				}
+				ts.Sensitive[key] = st
+				if ts.EstimatedRows > 0 {
+					sensitive[st] = true
+				}
+			}
+		}
+
+		sum.Rows += ts.Rows
+		sum.EstimatedRows += ts.EstimatedRows
+		sum.Tables = append(sum.Tables, ts)
+	}
+	for st := range sensitive {
+		sum.Sensitive = append(sum.Sensitive, st)
+	}
+	sort.Strings(sum.Sensitive)
+
+	sort.SliceStable(sum.Tables, func(i, j int) bool { return sum.Tables[i].EstimatedRows > sum.Tables[j].EstimatedRows })
+	if s.MaxTables > 0 && len(sum.Tables) > s.MaxTables {
+		sum.Tables = sum.Tables[:s.MaxTables]
+	}
+	return sum
+}
+
+// sequenceEstimate returns the row count given by the sequence of t, named
+// after it (SQLite) or prefixed by its name (PostgreSQL, e.g. users_id_seq)
+// when no other table matches it better.
+func sequenceEstimate(dump *dump, t *table) int {
+	n := 0
+	for seq, v := range dump.estimates {
+		seq = strings.ToLower(seq)
+		if seq != strings.ToLower(t.name) && !strings.HasPrefix(seq, strings.ToLower(t.name)+"_") {
+			continue
+		}
+		best := true
+		for _, o := range dump.tables {
+			if len(o.name) > len(t.name) && (seq == strings.ToLower(o.name) || strings.HasPrefix(seq, strings.ToLower(o.name)+"_")) {
+				best = false
+			}
+		}
+		if best && v > n {
+			n = v
+		}
+	}
+	return n
+}
@@ -0,0 +1,81 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package stackexchange
+
+import (
+	"fmt"
+	"html"
+)
+
+// wrapper is the common envelope of every Stack Exchange API response.
+// https://api.stackexchange.com/docs/wrapper
+type wrapper struct {
+	Items          []post `json:"items"`
+	HasMore        bool   `json:"has_more"`
+	QuotaMax       int    `json:"quota_max"`
+	QuotaRemaining int    `json:"quota_remaining"`
+	Backoff        int    `json:"backoff"`
+
+	ErrorID      int    `json:"error_id"`
+	ErrorName    string `json:"error_name"`
+	ErrorMessage string `json:"error_message"`
+}
+
+func (w *wrapper) err() error {
+	if w.ErrorID == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s (%d): %s", w.ErrorName, w.ErrorID, w.ErrorMessage)
+}
+
+// post holds the fields shared by questions, answers and generic posts.
+type post struct {
+	QuestionID       int64  `json:"question_id"`
+	AnswerID         int64  `json:"answer_id"`
+	PostID           int64  `json:"post_id"`
+	PostType         string `json:"post_type"`
+	Title            string `json:"title"`
+	Body             string `json:"body"`
+	Link             string `json:"link"`
+	CreationDate     int64  `json:"creation_date"`
+	LastEditDate     int64  `json:"last_edit_date"`
+	LastActivityDate int64  `json:"last_activity_date"`
+	Owner            struct {
+		DisplayName string `json:"display_name"`
+	} `json:"owner"`
+}
+
+// id returns the identifier of the post, whichever endpoint it came from.
+func (p *post) id() int64 {
+	switch {
+	case p.PostID != 0:
+		return p.PostID
+	case p.AnswerID != 0:
+		return p.AnswerID
+	default:
+		return p.QuestionID
+	}
+}
+
+// content returns the unescaped body of the post, prefixed by its title if any.
+func (p *post) content() []byte {
+	body := html.UnescapeString(p.Body)
+	if p.Title != "" {
+		body = html.UnescapeString(p.Title) + "\n\n" + body
+	}
+	return []byte(body)
+}
@@ -0,0 +1,325 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package stackexchange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/stopper"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	apiURL = "https://api.stackexchange.com/2.2"
+
+	postTypeQuestion = "question"
+	postTypeAnswer   = "answer"
+	postTypeEdit     = "edit"
+
+	// The API asks clients to wait a while when throttled, without telling for how long.
+	throttleBackoff = 60 * time.Second
+	// The API does not allow more than 100 items per page.
+	maxPageSize = 100
+)
+
+var (
+	errQuotaExceeded = errors.New("daily quota exceeded")
+)
+
+type stackexchange struct {
+	Interval time.Duration
+	Timeout  time.Duration
+	URL      string
+	Key      string
+	Sites    []string
+	Types    []string
+	PageSize int
+	MaxPages int
+
+	// Cursors hold, per site and post type, the date of the most recent post listed.
+	cursors map[string]int64
+
+	quotaM         sync.Mutex
+	quotaRemaining int
+	quotaReset     time.Time
+	backoffs       map[string]time.Time
+}
+
+func init() {
+	modules.Register("stackexchange", &stackexchange{})
+}
+
+func (s *stackexchange) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	s.Interval = 60 * time.Second
+	s.Timeout = 10 * time.Second
+	s.URL = apiURL
+	s.Sites = []string{"stackoverflow"}
+	s.Types = []string{postTypeQuestion, postTypeAnswer, postTypeEdit}
+	s.PageSize = maxPageSize
+	s.MaxPages = 3
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, s); err != nil {
+		return err
+	}
+	s.URL = strings.TrimSuffix(s.URL, "/")
+	if s.PageSize <= 0 || s.PageSize > maxPageSize {
+		s.PageSize = maxPageSize
+	}
+	if s.MaxPages <= 0 {
+		s.MaxPages = 1
+	}
+	for _, t := range s.Types {
+		if t != postTypeQuestion && t != postTypeAnswer && t != postTypeEdit {
+			return fmt.Errorf("unknown post type %q", t)
+		}
+	}
+
+	s.cursors = make(map[string]int64)
+	s.backoffs = make(map[string]time.Time)
+	s.quotaRemaining = -1
+
+	return nil
+}
+
+func (s *stackexchange) List(st *stopper.Stopper, ch chan *vautour.Document) error {
+	if s.Interval <= 0 {
+		<-st.Chan()
+		return nil
+	}
+
+	t := time.NewTicker(s.Interval)
+	defer t.Stop()
+	for {
+		// Wait for next loop.
+		select {
+		case <-st.Chan():
+			return nil
+		case <-t.C:
+		}
+
+		for _, site := range s.Sites {
+			for _, pType := range s.Types {
+				if err := s.list(site, pType, ch); err != nil {
+					log.WithField("role", "lister").WithField("module", "stackexchange").WithField("site", site).WithField("type", pType).WithError(err).Warn("failed to list new posts")
+				}
+			}
+		}
+	}
+}
+
+// list yields the posts of the given type that were created (or edited) on the
+// site since the last time it was called.
+func (s *stackexchange) list(site, pType string, ch chan *vautour.Document) error {
+	cursorKey := site + "/" + pType
+	cursor, ok := s.cursors[cursorKey]
+	if !ok {
+		// Only look at the posts published from now on.
+		cursor = time.Now().Add(-s.Interval).Unix()
+	}
+
+	var method string
+	params := url.Values{}
+	params.Set("site", site)
+	params.Set("order", "desc")
+	params.Set("min", strconv.FormatInt(cursor, 10))
+	params.Set("pagesize", strconv.Itoa(s.PageSize))
+	params.Set("filter", "withbody")
+	switch pType {
+	case postTypeQuestion:
+		method = "/questions"
+		params.Set("sort", "creation")
+	case postTypeAnswer:
+		method = "/answers"
+		params.Set("sort", "creation")
+	case postTypeEdit:
+		method = "/posts"
+		params.Set("sort", "activity")
+	}
+
+	newCursor := cursor
+	for page := 1; page <= s.MaxPages; page++ {
+		params.Set("page", strconv.Itoa(page))
+
+		w, err := s.get(method, params)
+		if err != nil {
+			return err
+		}
+
+		for i := range w.Items {
+			p := &w.Items[i]
+
+			d := &vautour.Document{
+				ID:        fmt.Sprintf("%s:%s:%d", site, pType, p.id()),
+				Title:     p.Title,
+				User:      p.Owner.DisplayName,
+				URL:       p.Link,
+				Content:   p.content(),
+				CreatedAt: time.Unix(p.CreationDate, 0),
+			}
+			d.Size = len(d.Content)
+			d.SetMetadata("site", site)
+			d.SetMetadata("type", pType)
+			if p.PostType != "" {
+				d.SetMetadata("post_type", p.PostType)
+			}
+
+			date := p.CreationDate
+			if pType == postTypeEdit {
+				// Activity also covers new comments & votes, only keep actual edits.
+				if p.LastEditDate < cursor {
+					continue
+				}
+				date = p.LastEditDate
+				d.ID = fmt.Sprintf("%s:%d", d.ID, p.LastEditDate)
+				d.CreatedAt = time.Unix(p.LastEditDate, 0)
+			}
+			if date > newCursor {
+				newCursor = date
+			}
+
+			ch <- d
+		}
+
+		if !w.HasMore {
+			break
+		}
+	}
+	s.cursors[cursorKey] = newCursor
+
+	return nil
+}
+
+func (s *stackexchange) Scrape(d *vautour.Document) error {
+	// The body is normally fetched at listing time already.
+	if len(d.Content) > 0 {
+		return nil
+	}
+
+	// ID format: site:type:id[:edit_date]
+	parts := strings.Split(d.ID, ":")
+	if len(parts) < 3 {
+		return fmt.Errorf("malformed document ID %q", d.ID)
+	}
+
+	params := url.Values{}
+	params.Set("site", parts[0])
+	params.Set("filter", "withbody")
+
+	w, err := s.get("/posts/"+parts[2], params)
+	if err != nil {
+		log.WithField("role", "scraper").WithField("module", "stackexchange").WithField("item_id", d.ID).WithError(err).Warn("failed to scrape post")
+		return err
+	}
+	if len(w.Items) == 0 {
+		return fmt.Errorf("post %s not found", d.ID)
+	}
+
+	d.Content = w.Items[0].content()
+	d.Size = len(d.Content)
+
+	return nil
+}
+
+// get queries the given API method, while respecting the daily quota and the
+// backoff periods requested by the API.
+func (s *stackexchange) get(method string, params url.Values) (*wrapper, error) {
+	if err := s.wait(method); err != nil {
+		return nil, err
+	}
+
+	if s.Key != "" {
+		params.Set("key", s.Key)
+	}
+
+	client := &http.Client{Timeout: s.Timeout}
+	res, err := client.Get(s.URL + method + "?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// Errors are returned as a wrapper too, with a non-200 status code.
+	var w wrapper
+	if err := json.NewDecoder(res.Body).Decode(&w); err != nil {
+		return nil, fmt.Errorf("failed to decode response (%s): %s", res.Status, err)
+	}
+	s.account(method, &w)
+
+	if err := w.err(); err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	return &w, nil
+}
+
+// wait blocks until the backoff period of the given method is over, or returns
+// an error if the daily quota has been exhausted.
+func (s *stackexchange) wait(method string) error {
+	s.quotaM.Lock()
+	if s.quotaRemaining == 0 && time.Now().Before(s.quotaReset) {
+		s.quotaM.Unlock()
+		return errQuotaExceeded
+	}
+	until := s.backoffs[methodName(method)]
+	s.quotaM.Unlock()
+
+	time.Sleep(time.Until(until))
+	return nil
+}
+
+// account records the quota and backoff information returned by the API.
+func (s *stackexchange) account(method string, w *wrapper) {
+	s.quotaM.Lock()
+	defer s.quotaM.Unlock()
+
+	method = methodName(method)
+
+	if w.QuotaMax > 0 {
+		s.quotaRemaining = w.QuotaRemaining
+		if s.quotaRemaining == 0 {
+			// Quotas are reset every day at midnight UTC.
+			s.quotaReset = time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+			log.WithField("module", "stackexchange").WithField("reset", s.quotaReset).Warn("daily quota exceeded")
+		}
+	}
+	if w.Backoff > 0 {
+		s.backoffs[method] = time.Now().Add(time.Duration(w.Backoff) * time.Second)
+	}
+	if w.ErrorName == "throttle_violation" {
+		s.backoffs[method] = time.Now().Add(throttleBackoff)
+	}
+}
+
+// methodName strips the IDs off a method path, as backoffs apply to every call
+// of the same method, whatever its parameters.
+func methodName(method string) string {
+	return strings.SplitN(strings.TrimPrefix(method, "/"), "/", 2)[0]
+}
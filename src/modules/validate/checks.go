@@ -0,0 +1,454 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package validate
+
+import (
+	"bytes"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	statusValid   = "valid"
+	statusInvalid = "invalid"
+	statusExpired = "expired"
+
+	base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// result is the outcome of a check.
+type result struct {
+	status  string
+	reason  string
+	details map[string]interface{}
+}
+
+func valid(details map[string]interface{}) result {
+	return result{status: statusValid, details: details}
+}
+
+func invalid(format string, args ...interface{}) result {
+	return result{status: statusInvalid, reason: fmt.Sprintf(format, args...)}
+}
+
+// check validates the structure of a kind of credentials, found by re.
+type check struct {
+	kind string
+	re   *regexp.Regexp
+	// sensitive is whether the matches must be redacted.
+	sensitive bool
+	validate  func(value string, now time.Time) result
+	// extend, if set, returns the end of the credential which header ends at
+	// the given offset.
+	extend func(content []byte, end int) int
+}
+
+var checks = []check{
+	{"github", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{10,}\b`), true, checkGitHub, nil},
+	{"github", regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{10,}\b`), true, checkGitHubPAT, nil},
+	{"aws", regexp.MustCompile(`\b(AKIA|ASIA|ABIA|ACCA|AGPA|AIDA|AIPA|ANPA|ANVA|AROA|APKA|ASCA)[A-Z0-9]{10,}\b`), true, checkAWS, nil},
+	{"slack", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`), true, checkSlack, nil},
+	{"slack", regexp.MustCompile(`https://hooks\.slack\.com/services/[A-Za-z0-9/]+`), true, checkSlackWebhook, nil},
+	{"stripe", regexp.MustCompile(`\b[spr]k_(live|test)_[A-Za-z0-9]{10,}\b`), true, checkStripe, nil},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*`), true, checkJWT, nil},
+	{"private-key", regexp.MustCompile(`-----BEGIN ([A-Z0-9]+ )*PRIVATE KEY-----`), true, checkPrivateKey, pemEnd},
+	{"certificate", regexp.MustCompile(`-----BEGIN CERTIFICATE-----`), false, checkCertificate, pemEnd},
+}
+
+// checkGitHub verifies the checksum of GitHub tokens: the last 6 characters
+// are the base62-encoded CRC32 of the 30 random ones.
+func checkGitHub(v string, _ time.Time) result {
+	body := v[4:]
+	if len(body) != 36 {
+		return invalid("expected 36 characters after the prefix, got %d", len(body))
+	}
+	random, checksum := body[:30], body[30:]
+
+	c := crc32.ChecksumIEEE([]byte(random))
+	var enc [6]byte
+	for i := len(enc) - 1; i >= 0; i-- {
+		enc[i] = base62[c%62]
+		c /= 62
+	}
+	if string(enc[:]) != checksum {
+		return invalid("checksum mismatch")
+	}
+	return valid(map[string]interface{}{"type": githubTypes[v[:3]]})
+}
+
+var githubTypes = map[string]string{
+	"ghp": "personal access token",
+	"gho": "oauth access token",
+	"ghu": "user-to-server token",
+	"ghs": "server-to-server token",
+	"ghr": "refresh token",
+}
+
+var githubPAT = regexp.MustCompile(`^github_pat_[A-Za-z0-9]{22}_[A-Za-z0-9]{59}$`)
+
+// checkGitHubPAT verifies the layout of fine-grained personal access tokens,
+// which checksum scheme is not public.
+func checkGitHubPAT(v string, _ time.Time) result {
+	if !githubPAT.MatchString(v) {
+		return invalid("malformed fine-grained personal access token")
+	}
+	return valid(map[string]interface{}{"type": "fine-grained personal access token"})
+}
+
+// checkAWS verifies the format of AWS access key IDs, and decodes the account
+// ID out of those created since 2019.
+func checkAWS(v string, _ time.Time) result {
+	if len(v) != 20 {
+		return invalid("expected 20 characters, got %d", len(v))
+	}
+	if strings.HasSuffix(v, "EXAMPLE") {
+		return invalid("documentation example")
+	}
+	b, err := base32.StdEncoding.DecodeString(v[4:])
+	if err != nil {
+		return invalid("not base32")
+	}
+
+	details := map[string]interface{}{"type": awsTypes[v[:4]]}
+	if v[4] != 'I' && v[4] != 'J' {
+		var n uint64
+		for _, c := range b[:6] {
+			n = n<<8 | uint64(c)
+		}
+		details["account"] = fmt.Sprintf("%012d", (n&0x7fffffffff80)>>7)
+	}
+	return valid(details)
+}
+
+var awsTypes = map[string]string{
+	"AKIA": "access key",
+	"ASIA": "temporary access key",
+	"ABIA": "bearer token",
+	"ACCA": "context-specific credential",
+	"AGPA": "group",
+	"AIDA": "user",
+	"AIPA": "instance profile",
+	"ANPA": "managed policy",
+	"ANVA": "managed policy version",
+	"AROA": "role",
+	"APKA": "public key",
+	"ASCA": "certificate",
+}
+
+var slackTokens = map[string]*regexp.Regexp{
+	"bot":  regexp.MustCompile(`^xoxb-\d{8,13}-\d{8,13}-[A-Za-z0-9]{24}$`),
+	"user": regexp.MustCompile(`^xoxp-\d{8,13}-\d{8,13}-\d{8,13}-[a-f0-9]{32}$`),
+}
+
+var slackOtherToken = regexp.MustCompile(`^xox[aors]-(\d+-){1,3}[A-Za-z0-9]{20,}$`)
+
+func checkSlack(v string, _ time.Time) result {
+	switch v[3] {
+	case 'b':
+		if !slackTokens["bot"].MatchString(v) {
+			return invalid("malformed bot token")
+		}
+		return valid(map[string]interface{}{"type": "bot token"})
+	case 'p':
+		if !slackTokens["user"].MatchString(v) {
+			return invalid("malformed user token")
+		}
+		return valid(map[string]interface{}{"type": "user token"})
+	}
+	if !slackOtherToken.MatchString(v) {
+		return invalid("malformed token")
+	}
+	return valid(map[string]interface{}{"type": "token"})
+}
+
+var slackWebhook = regexp.MustCompile(`^https://hooks\.slack\.com/services/T[A-Z0-9]{8,}/B[A-Z0-9]{8,}/[A-Za-z0-9]{24}$`)
+
+func checkSlackWebhook(v string, _ time.Time) result {
+	if !slackWebhook.MatchString(v) {
+		return invalid("malformed webhook")
+	}
+	return valid(map[string]interface{}{"type": "webhook"})
+}
+
+var stripeTypes = map[byte]string{'s': "secret key", 'r': "restricted key", 'p': "publishable key"}
+
+func checkStripe(v string, _ time.Time) result {
+	i := strings.LastIndexByte(v, '_')
+	if n := len(v) - i - 1; n < 24 || n > 247 {
+		return invalid("expected 24 to 247 characters after the prefix, got %d", n)
+	}
+	return valid(map[string]interface{}{"type": stripeTypes[v[0]], "mode": v[3:i]})
+}
+
+// checkJWT parses the header & payload of JSON Web Tokens, and verifies their
+// expiry (the signature cannot be verified offline).
+func checkJWT(v string, now time.Time) result {
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return invalid("expected 3 parts, got %d", len(parts))
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Typ string `json:"typ"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return invalid("malformed header: %s", err)
+	}
+	if header.Alg == "" {
+		return invalid("no algorithm")
+	}
+	unsigned := strings.EqualFold(header.Alg, "none")
+	if parts[2] == "" && !unsigned {
+		return invalid("missing signature")
+	}
+
+	var payload map[string]interface{}
+	if err := decodeJWTPart(parts[1], &payload); err != nil {
+		return invalid("malformed payload: %s", err)
+	}
+
+	details := map[string]interface{}{"alg": header.Alg}
+	if header.Kid != "" {
+		details["kid"] = header.Kid
+	}
+	for _, claim := range []string{"iss", "sub", "aud"} {
+		if c, ok := payload[claim]; ok {
+			details[claim] = c
+		}
+	}
+	if unsigned {
+		details["unsigned"] = true
+	}
+
+	if exp, ok := payload["exp"].(json.Number); ok {
+		if sec, err := exp.Float64(); err == nil {
+			t := time.Unix(int64(sec), 0).UTC()
+			details["exp"] = t
+			if t.Before(now) {
+				return result{status: statusExpired, reason: "expired on " + t.Format(time.RFC3339), details: details}
+			}
+		}
+	}
+	return valid(details)
+}
+
+func decodeJWTPart(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// checkPrivateKey parses PEM private keys, to tell their type & size.
+func checkPrivateKey(v string, _ time.Time) result {
+	block, err := decodePEM(v)
+	if err != nil {
+		return invalid("%s", err)
+	}
+
+	details := map[string]interface{}{"pem": block.Type}
+	if x509.IsEncryptedPEMBlock(block) || block.Type == "ENCRYPTED PRIVATE KEY" {
+		// Passphrase-protected keys cannot be parsed, but are well-formed PEM.
+		details["encrypted"] = true
+		return valid(details)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "OPENSSH PRIVATE KEY":
+		var r result
+		if r, err = parseOpenSSHKey(block.Bytes, details); err == nil {
+			return r
+		}
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return invalid("unparsable key: %s", err)
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		details["type"], details["bits"] = "rsa", k.N.BitLen()
+	case *ecdsa.PrivateKey:
+		details["type"], details["bits"] = "ecdsa", k.Curve.Params().BitSize
+	case ed25519.PrivateKey:
+		details["type"], details["bits"] = "ed25519", 256
+	case *dsa.PrivateKey:
+		details["type"], details["bits"] = "dsa", k.P.BitLen()
+	default:
+		details["type"] = fmt.Sprintf("%T", key)
+	}
+	return valid(details)
+}
+
+// parseOpenSSHKey reads the type & size of an openssh-key-v1 key out of its
+// public part, which is stored in the clear even when the key is encrypted.
+func parseOpenSSHKey(b []byte, details map[string]interface{}) (result, error) {
+	const magic = "openssh-key-v1\x00"
+	if !bytes.HasPrefix(b, []byte(magic)) {
+		return result{}, errors.New("not an openssh-key-v1 key")
+	}
+	r := &sshReader{b: b[len(magic):]}
+
+	cipher := string(r.string())
+	r.string() // kdf name
+	r.string() // kdf options
+	r.uint32() // number of keys
+	pub := &sshReader{b: r.string()}
+	if r.err != nil {
+		return result{}, r.err
+	}
+	if cipher != "none" {
+		details["encrypted"] = true
+	}
+
+	switch keyType := string(pub.string()); {
+	case keyType == "ssh-rsa":
+		pub.string() // e
+		details["type"], details["bits"] = "rsa", new(big.Int).SetBytes(pub.string()).BitLen()
+	case keyType == "ssh-ed25519":
+		details["type"], details["bits"] = "ed25519", 8*len(pub.string())
+	case strings.HasPrefix(keyType, "ecdsa-sha2-nistp"):
+		var bits int
+		fmt.Sscanf(keyType, "ecdsa-sha2-nistp%d", &bits)
+		details["type"], details["bits"] = "ecdsa", bits
+	case keyType == "ssh-dss":
+		details["type"], details["bits"] = "dsa", new(big.Int).SetBytes(pub.string()).BitLen()
+	default:
+		details["type"] = keyType
+	}
+	if pub.err != nil {
+		return result{}, pub.err
+	}
+	return valid(details), nil
+}
+
+// sshReader reads the wire encoding of SSH (RFC 4251), recording the first
+// error.
+type sshReader struct {
+	b   []byte
+	err error
+}
+
+func (r *sshReader) uint32() uint32 {
+	if r.err != nil || len(r.b) < 4 {
+		r.err = errors.New("truncated key")
+		return 0
+	}
+	n := binary.BigEndian.Uint32(r.b)
+	r.b = r.b[4:]
+	return n
+}
+
+func (r *sshReader) string() []byte {
+	n := r.uint32()
+	if r.err != nil || uint32(len(r.b)) < n {
+		r.err = errors.New("truncated key")
+		return nil
+	}
+	s := r.b[:n]
+	r.b = r.b[n:]
+	return s
+}
+
+// checkCertificate parses X.509 certificates, and verifies their validity
+// period.
+func checkCertificate(v string, now time.Time) result {
+	block, err := decodePEM(v)
+	if err != nil {
+		return invalid("%s", err)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return invalid("unparsable certificate: %s", err)
+	}
+
+	details := map[string]interface{}{
+		"subject":   cert.Subject.String(),
+		"issuer":    cert.Issuer.String(),
+		"notbefore": cert.NotBefore.UTC(),
+		"notafter":  cert.NotAfter.UTC(),
+		"ca":        cert.IsCA,
+	}
+	if len(cert.DNSNames) > 0 {
+		details["dns_names"] = cert.DNSNames
+	}
+	switch {
+	case now.After(cert.NotAfter):
+		return result{status: statusExpired, reason: "expired on " + cert.NotAfter.UTC().Format(time.RFC3339), details: details}
+	case now.Before(cert.NotBefore):
+		return result{status: statusInvalid, reason: "not valid before " + cert.NotBefore.UTC().Format(time.RFC3339), details: details}
+	}
+	return valid(details)
+}
+
+// pemEnd returns the end of the PEM block which header ends at the given
+// offset: after its footer, or before the next block if it is truncated.
+func pemEnd(content []byte, end int) int {
+	rest := content[end:]
+	next := bytes.Index(rest, []byte("-----BEGIN "))
+	if next < 0 {
+		next = len(rest)
+	}
+
+	footer := bytes.Index(rest[:next], []byte("-----END "))
+	if footer < 0 {
+		return end + next
+	}
+	if i := bytes.Index(rest[footer+len("-----END "):next], []byte("-----")); i >= 0 {
+		return end + footer + len("-----END ") + i + len("-----")
+	}
+	return end + next
+}
+
+// decodePEM decodes a PEM block, possibly embedded in a string literal with
+// escaped line breaks.
+func decodePEM(v string) (*pem.Block, error) {
+	v = strings.Replace(v, `\r\n`, "\n", -1)
+	v = strings.Replace(v, `\n`, "\n", -1)
+
+	block, _ := pem.Decode([]byte(v))
+	if block == nil {
+		return nil, errors.New("truncated or malformed PEM block")
+	}
+	return block, nil
+}
@@ -0,0 +1,174 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/secret"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// validate checks the structure of the credentials found in documents
+// (checksums, formats, expiry), without any network call, so that fake or
+// truncated ones can be told apart from the real ones. It is meant to run
+// after the detection processors.
+type validate struct {
+	// ValidScore is the score of the documents holding valid credentials.
+	ValidScore int
+	// InvalidPenalty is removed from the score of the documents which
+	// credentials are all invalid or expired. The detectors' results about
+	// invalid or expired credentials only are cancelled in any case.
+	InvalidPenalty int
+	// MaxFindings is the maximum number of findings per document.
+	MaxFindings int
+}
+
+// finding is the ProcessedData of a validated credential.
+type finding struct {
+	Type    string
+	Status  string
+	Reason  string `json:",omitempty"`
+	Line    int
+	Value   string                 `json:",omitempty"`
+	Details map[string]interface{} `json:",omitempty"`
+}
+
+func init() {
+	modules.Register("validate", &validate{})
+}
+
+func (v *validate) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	v.ValidScore = 30
+	v.InvalidPenalty = 5
+	v.MaxFindings = 50
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, v); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (v *validate) Process(d *vautour.Document) error {
+	now := time.Now()
+	counts := make(map[string]int)
+	// The results of the detectors, which run before.
+	detected := len(d.Processed)
+	var valid, invalid []vautour.Span
+
+	// first is the index of the first invalid or expired credential.
+	n, first := 0, -1
+	for _, c := range checks {
+		for _, m := range c.re.FindAllIndex(d.Content, -1) {
+			if v.MaxFindings > 0 && n >= v.MaxFindings {
+				break
+			}
+			n++
+			if c.extend != nil {
+				m[1] = c.extend(d.Content, m[1])
+			}
+
+			value := string(d.Content[m[0]:m[1]])
+			r := c.validate(value, now)
+			f := finding{
+				Type:    c.kind,
+				Status:  r.status,
+				Reason:  r.reason,
+				Line:    bytes.Count(d.Content[:m[0]], []byte("\n")) + 1,
+				Details: r.details,
+			}
+			if c.sensitive {
+				d.MarkSensitive(m[0], m[1])
+				if !strings.HasPrefix(value, "-----") {
+					// PEM blocks are described by their details instead.
+					f.Value = secret.Redact(value)
+				}
+			}
+
+			j, err := json.Marshal(f)
+			if err != nil {
+				log.WithField("role", "processor").WithField("module", "validate").WithField("item_id", d.ID).Warn("failed to marshal finding")
+				continue
+			}
+			pd := vautour.ProcessedData{
+				Module:     "validate",
+				Spans:      []vautour.Span{{Start: m[0], End: m[1]}},
+				RawMessage: j,
+			}
+			// Certificates are public, they do not affect the score.
+			if c.sensitive {
+				counts[r.status]++
+				if r.status == statusValid {
+					pd.Score = v.ValidScore
+					valid = append(valid, pd.Spans...)
+				} else {
+					invalid = append(invalid, pd.Spans...)
+					if first < 0 {
+						first = len(d.Processed)
+					}
+				}
+			}
+			d.Processed = append(d.Processed, pd)
+		}
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+
+	// The results about invalid credentials only are cancelled, so that a fake
+	// key does not keep the score its detector gave it.
+	cancelled := 0
+	for i, p := range d.Processed[:detected] {
+		if p.Score > 0 && overlaps(p.Spans, invalid) && !overlaps(p.Spans, valid) {
+			d.Processed[i].Score = 0
+			cancelled++
+		}
+	}
+	if counts[statusValid] == 0 {
+		// The penalty is contributed once, by the first invalid credential.
+		d.Processed[first].Score = -v.InvalidPenalty
+	}
+
+	if counts[statusValid] > 0 {
+		d.AddTags("credential-valid")
+	}
+	if counts[statusExpired] > 0 {
+		d.AddTags("credential-expired")
+	}
+	log.WithField("role", "processor").WithField("module", "validate").WithField("item_id", d.ID).WithField("valid", counts[statusValid]).WithField("invalid", counts[statusInvalid]).WithField("expired", counts[statusExpired]).WithField("cancelled", cancelled).Debug("validated credentials")
+
+	return nil
+}
+
+// overlaps returns whether any of the spans overlaps any of the others.
+func overlaps(spans, others []vautour.Span) bool {
+	for _, s := range spans {
+		for _, o := range others {
+			if s.Start < o.End && o.Start < s.End {
+				return true
+			}
+		}
+	}
+	return false
+}
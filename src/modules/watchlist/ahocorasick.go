@@ -0,0 +1,108 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package watchlist
+
+// automaton is an Aho-Corasick automaton, matching a set of patterns in a
+// single pass over the text.
+// https://en.wikipedia.org/wiki/Aho%E2%80%93Corasick_algorithm
+type automaton struct {
+	nodes []acNode
+	// lengths of the patterns, by index.
+	lengths []int
+}
+
+type acNode struct {
+	next map[byte]int
+	fail int
+	// out holds the patterns ending at this node, including through the
+	// failure links.
+	out []int
+}
+
+func newAutomaton(patterns [][]byte) *automaton {
+	a := &automaton{nodes: []acNode{{next: make(map[byte]int)}}}
+
+	// Build the trie.
+	for i, p := range patterns {
+		a.lengths = append(a.lengths, len(p))
+		if len(p) == 0 {
+			continue
+		}
+		n := 0
+		for _, c := range p {
+			next, ok := a.nodes[n].next[c]
+			if !ok {
+				next = len(a.nodes)
+				a.nodes = append(a.nodes, acNode{next: make(map[byte]int)})
+				a.nodes[n].next[c] = next
+			}
+			n = next
+		}
+		a.nodes[n].out = append(a.nodes[n].out, i)
+	}
+
+	// Set the failure links, breadth-first.
+	var queue []int
+	for _, child := range a.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		for c, child := range a.nodes[n].next {
+			queue = append(queue, child)
+
+			f := a.nodes[n].fail
+			for {
+				if next, ok := a.nodes[f].next[c]; ok && next != child {
+					a.nodes[child].fail = next
+					break
+				}
+				if f == 0 {
+					a.nodes[child].fail = 0
+					break
+				}
+				f = a.nodes[f].fail
+			}
+			a.nodes[child].out = append(a.nodes[child].out, a.nodes[a.nodes[child].fail].out...)
+		}
+	}
+
+	return a
+}
+
+// match calls f with the index & the start offset of every occurrence of the
+// patterns in the text.
+func (a *automaton) match(text []byte, f func(pattern, start int)) {
+	n := 0
+	for i, c := range text {
+		for {
+			if next, ok := a.nodes[n].next[c]; ok {
+				n = next
+				break
+			}
+			if n == 0 {
+				break
+			}
+			n = a.nodes[n].fail
+		}
+		for _, p := range a.nodes[n].out {
+			f(p, i+1-a.lengths[p])
+		}
+	}
+}
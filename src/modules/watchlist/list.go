@@ -0,0 +1,173 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package watchlist
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// list holds the terms loaded from the term files, compiled into two
+// automatons: one for the case-sensitive terms, matched on the content as-is,
+// and one for the others, matched on the lowercased content.
+type list struct {
+	terms []term
+	// modTimes are the modification times of the files when loaded.
+	modTimes map[string]time.Time
+
+	sensitive, insensitive           *automaton
+	sensitiveTerms, insensitiveTerms []int
+}
+
+type term struct {
+	value string
+	file  *fileConfig
+}
+
+// load reads the term files and builds their automatons.
+func load(files []fileConfig) (*list, error) {
+	l := &list{modTimes: make(map[string]time.Time)}
+	var sensitive, insensitive [][]byte
+
+	for i := range files {
+		f := &files[i]
+		if f.Path == "" {
+			return nil, fmt.Errorf("term file #%d has no path", i)
+		}
+		if f.Category == "" {
+			f.Category = "watchlist"
+		}
+
+		fi, err := os.Stat(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		l.modTimes[f.Path] = fi.ModTime()
+
+		values, err := readTerms(f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read term file %q: %s", f.Path, err)
+		}
+		for _, v := range values {
+			t := len(l.terms)
+			l.terms = append(l.terms, term{value: v, file: f})
+			if f.CaseSensitive {
+				sensitive = append(sensitive, []byte(v))
+				l.sensitiveTerms = append(l.sensitiveTerms, t)
+			} else {
+				insensitive = append(insensitive, toLowerASCII([]byte(v)))
+				l.insensitiveTerms = append(l.insensitiveTerms, t)
+			}
+		}
+	}
+
+	l.sensitive = newAutomaton(sensitive)
+	l.insensitive = newAutomaton(insensitive)
+	return l, nil
+}
+
+// readTerms returns the terms of a file, one per line. Empty lines & lines
+// starting with # are ignored.
+func readTerms(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var terms []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		terms = append(terms, line)
+	}
+	return terms, s.Err()
+}
+
+// changed returns whether any of the files was modified or removed since the
+// list was loaded.
+func (l *list) changed() bool {
+	for path, modTime := range l.modTimes {
+		fi, err := os.Stat(path)
+		if err != nil || !fi.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// match calls f with the index & the start offset of every occurrence of the
+// terms in the content, honoring their word-boundary flag.
+func (l *list) match(content []byte, f func(term, start int)) {
+	report := func(terms []int) func(p, start int) {
+		return func(p, start int) {
+			t := terms[p]
+			end := start + len(l.terms[t].value)
+			if l.terms[t].file.WordBoundary && !(boundary(content, start, true) && boundary(content, end, false)) {
+				return
+			}
+			f(t, start)
+		}
+	}
+
+	if len(l.sensitiveTerms) > 0 {
+		l.sensitive.match(content, report(l.sensitiveTerms))
+	}
+	if len(l.insensitiveTerms) > 0 {
+		// ASCII lowercasing keeps the offsets of the original content, unlike
+		// bytes.ToLower which may change the length of some runes. Non-ASCII
+		// letters are thus matched case-sensitively.
+		l.insensitive.match(toLowerASCII(content), report(l.insensitiveTerms))
+	}
+}
+
+// boundary returns whether the rune before (or after) offset i is not part
+// of a word.
+func boundary(content []byte, i int, before bool) bool {
+	var r rune
+	if before {
+		if i <= 0 {
+			return true
+		}
+		r, _ = utf8.DecodeLastRune(content[:i])
+	} else {
+		if i >= len(content) {
+			return true
+		}
+		r, _ = utf8.DecodeRune(content[i:])
+	}
+	return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func toLowerASCII(b []byte) []byte {
+	l := make([]byte, len(b))
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		l[i] = c
+	}
+	return l
+}
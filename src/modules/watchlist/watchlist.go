@@ -0,0 +1,183 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package watchlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+// watchlist looks for the terms of an organization's watchlist (brands,
+// employees, projects, ...) in documents. The term files are reloaded when
+// they change.
+type watchlist struct {
+	Files []fileConfig
+	// ReloadInterval is how often the files are checked for changes.
+	ReloadInterval time.Duration
+	// Context is the number of bytes of context reported around a match.
+	Context int
+	// MaxOffsets is the maximum number of offsets reported per term.
+	MaxOffsets int
+
+	m          sync.RWMutex
+	list       *list
+	lastReload time.Time
+}
+
+// fileConfig describes a term file, with one term per line. Empty lines &
+// lines starting with # are ignored.
+type fileConfig struct {
+	Path          string
+	Category      string
+	Score         int
+	CaseSensitive bool
+	WordBoundary  bool
+}
+
+// match is the ProcessedData of a term found in a document.
+type match struct {
+	Term     string
+	Category string
+	Count    int
+	Offsets  []int
+	Context  string
+}
+
+func init() {
+	modules.Register("watchlist", &watchlist{})
+}
+
+func (w *watchlist) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	w.ReloadInterval = 30 * time.Second
+	w.Context = 40
+	w.MaxOffsets = 10
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, w); err != nil {
+		return err
+	}
+	if len(w.Files) == 0 {
+		return errors.New("no term file configured")
+	}
+
+	l, err := load(w.Files)
+	if err != nil {
+		return err
+	}
+	w.list = l
+	w.lastReload = time.Now()
+
+	return nil
+}
+
+func (w *watchlist) Process(d *vautour.Document) error {
+	l := w.current()
+
+	matches := make(map[int]*match)
+	var order []int
+	l.match(d.Content, func(t int, start int) {
+		m, ok := matches[t]
+		if !ok {
+			term := l.terms[t]
+			m = &match{
+				Term:     term.value,
+				Category: term.file.Category,
+				Context:  context(d.Content, start, start+len(term.value), w.Context),
+			}
+			matches[t] = m
+			order = append(order, t)
+		}
+		m.Count++
+		if w.MaxOffsets <= 0 || len(m.Offsets) < w.MaxOffsets {
+			m.Offsets = append(m.Offsets, start)
+		}
+	})
+
+	for _, t := range order {
+		j, err := json.Marshal(matches[t])
+		if err != nil {
+			log.WithField("role", "processor").WithField("module", "watchlist").WithField("item_id", d.ID).Warn("failed to marshal match")
+			continue
+		}
+		f := l.terms[t].file
+		d.Processed = append(d.Processed, vautour.ProcessedData{
+			Module:     "watchlist",
+			Score:      f.Score,
+			RawMessage: j,
+		})
+		d.AddTags(f.Category)
+	}
+	if len(order) > 0 {
+		log.WithField("role", "processor").WithField("module", "watchlist").WithField("item_id", d.ID).WithField("terms", len(order)).Debug("matched watchlist")
+	}
+
+	return nil
+}
+
+// current returns the loaded list, after reloading it if the files changed
+// since the last check.
+func (w *watchlist) current() *list {
+	w.m.RLock()
+	l, due := w.list, w.ReloadInterval > 0 && time.Since(w.lastReload) >= w.ReloadInterval
+	w.m.RUnlock()
+	if !due {
+		return l
+	}
+
+	w.m.Lock()
+	defer w.m.Unlock()
+	if time.Since(w.lastReload) < w.ReloadInterval {
+		// Reloaded by another routine meanwhile.
+		return w.list
+	}
+	w.lastReload = time.Now()
+
+	if !w.list.changed() {
+		return w.list
+	}
+	nl, err := load(w.Files)
+	if err != nil {
+		log.WithField("role", "processor").WithField("module", "watchlist").WithError(err).Warn("failed to reload term files, keeping the previous ones")
+		return w.list
+	}
+	w.list = nl
+	log.WithField("role", "processor").WithField("module", "watchlist").WithField("terms", len(nl.terms)).Info("reloaded term files")
+
+	return w.list
+}
+
+// context returns the text around content[start:end], on a single line.
+func context(content []byte, start, end, size int) string {
+	from, to := start-size, end+size
+	if from < 0 {
+		from = 0
+	}
+	if to > len(content) {
+		to = len(content)
+	}
+
+	c := bytes.ToValidUTF8(content[from:to], nil)
+	return strings.Join(strings.Fields(string(c)), " ")
+}
@@ -0,0 +1,118 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// document is the JSON representation of a submitted document. The content
+// is either given as text, or base64-encoded for binary data.
+type document struct {
+	ID            string    `json:"id"`
+	Title         string    `json:"title"`
+	User          string    `json:"user"`
+	URL           string    `json:"url"`
+	CreatedAt     time.Time `json:"created_at"`
+	Content       string    `json:"content"`
+	ContentBase64 []byte    `json:"content_base64"`
+
+	Metadata vautour.Metadata `json:"metadata"`
+	Tags     []string         `json:"tags"`
+}
+
+// parseJSON parses either a single document, or an array of documents.
+func parseJSON(b []byte) ([]*vautour.Document, error) {
+	var ps []document
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '[' {
+		if err := json.Unmarshal(b, &ps); err != nil {
+			return nil, err
+		}
+	} else {
+		var p document
+		if err := json.Unmarshal(b, &p); err != nil {
+			return nil, err
+		}
+		ps = append(ps, p)
+	}
+
+	ds := make([]*vautour.Document, 0, len(ps))
+	for _, p := range ps {
+		content := p.ContentBase64
+		if content == nil {
+			content = []byte(p.Content)
+		}
+		if len(content) == 0 {
+			return nil, errors.New("document without content")
+		}
+		d := newDocument(p.ID, p.Title, p.User, p.URL, p.CreatedAt, content)
+		for k, v := range p.Metadata {
+			d.SetMetadata(k, v)
+		}
+		d.AddTags(p.Tags...)
+		ds = append(ds, d)
+	}
+	return ds, nil
+}
+
+// parseRaw builds a document out of a raw body, reading its metadata from the
+// request headers.
+func parseRaw(h http.Header, b []byte) *vautour.Document {
+	d := newDocument(h.Get(headerID), h.Get(headerTitle), h.Get(headerUser), h.Get(headerURL), time.Time{}, b)
+
+	// Metadata keys are lowercased, as header names are case-insensitive.
+	for k, vs := range h {
+		if strings.HasPrefix(k, headerMetaPrefix) && len(k) > len(headerMetaPrefix) && len(vs) > 0 {
+			d.SetMetadata(strings.ToLower(k[len(headerMetaPrefix):]), vs[0])
+		}
+	}
+	for _, v := range h[headerTags] {
+		for _, t := range strings.Split(v, ",") {
+			d.AddTags(strings.TrimSpace(t))
+		}
+	}
+	return d
+}
+
+func newDocument(id, title, user, url string, createdAt time.Time, content []byte) *vautour.Document {
+	// Without ID, identical submissions are deduplicated by content.
+	if id == "" {
+		h := sha256.Sum256(content)
+		id = hex.EncodeToString(h[:])
+	}
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	return &vautour.Document{
+		ID:        id,
+		Title:     title,
+		User:      user,
+		URL:       url,
+		CreatedAt: createdAt,
+		Content:   content,
+		Size:      len(content),
+	}
+}
@@ -0,0 +1,209 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/stopper"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	shutdownTimeout = 10 * time.Second
+	restartDelay    = 5 * time.Second
+
+	// Headers carrying the metadata of raw documents.
+	headerID    = "X-Vautour-Id"
+	headerTitle = "X-Vautour-Title"
+	headerUser  = "X-Vautour-User"
+	headerURL   = "X-Vautour-Url"
+	headerTags  = "X-Vautour-Tags"
+	// headerMetaPrefix prefixes headers carrying arbitrary metadata, e.g.
+	// X-Vautour-Meta-Syntax.
+	headerMetaPrefix = "X-Vautour-Meta-"
+)
+
+// webhook is a push input: it runs an HTTP server to which documents are
+// submitted, and yields them with their content already attached.
+type webhook struct {
+	Addr     string
+	Path     string
+	CertFile string
+	KeyFile  string
+	MaxSize  int64
+
+	// Tokens are accepted as `Authorization: Bearer <token>`.
+	Tokens []string
+	// Secret is the HMAC-SHA256 key of the body signature, sent hex-encoded
+	// in SignatureHeader, optionally prefixed by `sha256=`.
+	Secret          string
+	SignatureHeader string
+	// Insecure allows to run without any authentication.
+	Insecure bool
+}
+
+func init() {
+	modules.Register("webhook", &webhook{})
+}
+
+func (w *webhook) Configure(cfg *modules.ModuleConfig) error {
+	// Default configuration.
+	w.Addr = ":8080"
+	w.Path = "/documents"
+	w.MaxSize = 10 * 1024 * 1024
+	w.SignatureHeader = "X-Vautour-Signature"
+
+	// Parse parameters.
+	if err := modules.ParseParams(cfg.Params, w); err != nil {
+		return err
+	}
+	w.Secret = os.ExpandEnv(w.Secret)
+	for i := range w.Tokens {
+		w.Tokens[i] = os.ExpandEnv(w.Tokens[i])
+	}
+	if len(w.Tokens) == 0 && w.Secret == "" && !w.Insecure {
+		return errors.New("no authentication configured, set tokens and/or secret (or insecure)")
+	}
+
+	return nil
+}
+
+func (w *webhook) List(st *stopper.Stopper, ch chan *vautour.Document) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(w.Path, func(rw http.ResponseWriter, r *http.Request) {
+		w.handle(st, ch, rw, r)
+	})
+	srv := &http.Server{Addr: w.Addr, Handler: mux}
+
+	errs := make(chan error, 1)
+	go func() {
+		log.WithField("role", "lister").WithField("module", "webhook").WithField("addr", w.Addr).Info("listening for documents")
+		if w.CertFile != "" {
+			errs <- srv.ListenAndServeTLS(w.CertFile, w.KeyFile)
+		} else {
+			errs <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errs:
+		// Avoid restarting in a tight loop if the address is unavailable.
+		st.Sleep(restartDelay)
+		return err
+	case <-st.Chan():
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	}
+}
+
+func (w *webhook) handle(st *stopper.Stopper, ch chan *vautour.Document, rw http.ResponseWriter, r *http.Request) {
+	logger := log.WithField("role", "lister").WithField("module", "webhook").WithField("remote", r.RemoteAddr)
+
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, w.MaxSize+1))
+	if err != nil {
+		http.Error(rw, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > w.MaxSize {
+		http.Error(rw, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !w.authenticate(r, body) {
+		logger.Warn("rejected unauthenticated request")
+		http.Error(rw, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var ds []*vautour.Document
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "application/json" {
+		if ds, err = parseJSON(body); err != nil {
+			http.Error(rw, fmt.Sprintf("invalid documents: %s", err), http.StatusBadRequest)
+			return
+		}
+	} else {
+		ds = []*vautour.Document{parseRaw(r.Header, body)}
+	}
+
+	for _, d := range ds {
+		select {
+		case ch <- d:
+			logger.WithField("item_id", d.ID).Debug("received document")
+		case <-st.Chan():
+			http.Error(rw, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	rw.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(rw, "%d document(s) accepted\n", len(ds))
+}
+
+// authenticate verifies that the request either bears one of the configured
+// tokens, or is signed with the configured secret.
+func (w *webhook) authenticate(r *http.Request, body []byte) bool {
+	if w.Insecure {
+		return true
+	}
+
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token := []byte(strings.TrimPrefix(auth, "Bearer "))
+		for _, t := range w.Tokens {
+			if subtle.ConstantTimeCompare(token, []byte(t)) == 1 {
+				return true
+			}
+		}
+	}
+
+	if sig := r.Header.Get(w.SignatureHeader); w.Secret != "" && sig != "" {
+		got, err := hex.DecodeString(strings.TrimPrefix(sig, "sha256="))
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(w.Secret))
+		mac.Write(body)
+		return hmac.Equal(got, mac.Sum(nil))
+	}
+
+	return false
+}
+
+// Scrape does nothing, as pushed documents come with their content.
+func (w *webhook) Scrape(d *vautour.Document) error {
+	return nil
+}
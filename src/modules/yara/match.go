@@ -0,0 +1,198 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package yara
+
+import (
+	"bytes"
+	lib "github.com/hillu/go-yara"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ruleMatch is the ProcessedData of a matched rule.
+type ruleMatch struct {
+	Rule      string
+	Namespace string                 `json:",omitempty"`
+	Tags      []string               `json:",omitempty"`
+	Meta      map[string]interface{} `json:",omitempty"`
+	Strings   []stringMatch          `json:",omitempty"`
+	// Truncated is set when only the first strings matches are listed.
+	Truncated bool `json:",omitempty"`
+}
+
+// stringMatch is a match of a string of a rule.
+type stringMatch struct {
+	Identifier string
+	Offset     uint64
+	Length     int
+	Line       int
+	// Data is the matched data, capped & with its non-printable characters
+	// replaced by dots.
+	Data string
+	// Context are the lines surrounding the match.
+	Context string `json:",omitempty"`
+}
+
+// newMatch converts a match of the library, with the given limits. The data &
+// context are read from the content with its sensitive spans masked, as well as
+// the matched data, unless RedactData is disabled.
+func (y *yara) newMatch(content []byte, sensitive []vautour.Span, m lib.MatchRule) ruleMatch {
+	r := ruleMatch{Rule: m.Rule, Namespace: m.Namespace, Tags: m.Tags, Meta: m.Meta}
+
+	var masked []byte
+	for _, s := range sensitive {
+		masked = mask(content, masked, s.Start, s.End, 0)
+	}
+	if y.RedactData {
+		for _, s := range m.Strings {
+			masked = mask(content, masked, int(s.Offset), int(s.Offset)+len(s.Data), 4)
+		}
+	}
+	if masked != nil {
+		content = masked
+	}
+
+	strs := append([]lib.MatchString(nil), m.Strings...)
+	sort.SliceStable(strs, func(i, j int) bool { return strs[i].Offset < strs[j].Offset })
+	if y.MaxStrings > 0 && len(strs) > y.MaxStrings {
+		strs = strs[:y.MaxStrings]
+		r.Truncated = true
+	}
+
+	for _, s := range strs {
+		start := int(s.Offset)
+		if start > len(content) {
+			start = len(content)
+		}
+		end := start + len(s.Data)
+		if end > len(content) {
+			end = len(content)
+		}
+
+		data := s.Data
+		if end-start == len(data) {
+			data = content[start:end]
+		} else if y.RedactData {
+			data = bytes.Repeat([]byte("*"), len(data))
+		}
+		if y.MaxDataLength > 0 && len(data) > y.MaxDataLength {
+			data = data[:y.MaxDataLength]
+		}
+		r.Strings = append(r.Strings, stringMatch{
+			Identifier: s.Name,
+			Offset:     s.Offset,
+			Length:     len(s.Data),
+			Line:       bytes.Count(content[:start], []byte("\n")) + 1,
+			Data:       printable(data),
+			Context:    printable(context(content, start, end, y.ContextLines, y.MaxContextLength)),
+		})
+	}
+	return r
+}
+
+// mask masks content[start:end] in a copy of content (created on first use),
+// but its first keep bytes when it is long enough for them not to give it away,
+// as secret.Redact does.
+func mask(content, masked []byte, start, end, keep int) []byte {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(content) {
+		end = len(content)
+	}
+	if start >= end {
+		return masked
+	}
+	if masked == nil {
+		masked = append([]byte(nil), content...)
+	}
+	if end-start >= 12 {
+		start += keep
+	}
+	for i := start; i < end; i++ {
+		masked[i] = '*'
+	}
+	return masked
+}
+
+// context returns the lines spanned by content[start:end], as well as n lines
+// before & after them, capped to max bytes around the match.
+func context(content []byte, start, end, n, max int) []byte {
+	if n < 0 {
+		return nil
+	}
+
+	cStart := start
+	for i := 0; i <= n; i++ {
+		j := bytes.LastIndexByte(content[:cStart], '\n')
+		if j < 0 {
+			cStart = 0
+			break
+		}
+		cStart = j
+		if i < n {
+			continue
+		}
+		cStart++
+	}
+	cEnd := end
+	for i := 0; i <= n; i++ {
+		j := bytes.IndexByte(content[cEnd:], '\n')
+		if j < 0 {
+			cEnd = len(content)
+			break
+		}
+		cEnd += j
+		if i < n {
+			cEnd++
+		}
+	}
+
+	// Long lines (e.g. minified code) are cut around the match.
+	if max > 0 && cEnd-cStart > max {
+		margin := (max - (end - start)) / 2
+		if margin < 0 {
+			margin = 0
+		}
+		if start-margin > cStart {
+			cStart = start - margin
+		}
+		if end+margin < cEnd {
+			cEnd = end + margin
+		}
+	}
+	return bytes.TrimRight(content[cStart:cEnd], "\r")
+}
+
+// printable returns b as text, with its invalid & non-printable characters
+// (but tabs & newlines) replaced by dots.
+func printable(b []byte) string {
+	var buf bytes.Buffer
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		switch {
+		case r == utf8.RuneError && size <= 1, r != '\n' && r != '\t' && !unicode.IsPrint(r):
+			buf.WriteByte('.')
+		default:
+			buf.WriteRune(r)
+		}
+		b = b[size:]
+	}
+	return buf.String()
+}
@@ -0,0 +1,135 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package yara
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	lib "github.com/hillu/go-yara"
+	"github.com/quentin-m/vautour/src/pkg/vautour"
+)
+
+// ruleSet is a source of YARA rules, compiled in its own namespace.
+type ruleSet struct {
+	// Path is a rule file, a directory (whose .yar & .yara files are compiled,
+	// recursively) or a glob pattern.
+	Path      string
+	Namespace string
+	Disabled  bool
+}
+
+// files returns the rule files of the set, in lexical order.
+func (s ruleSet) files() ([]string, error) {
+	paths := []string{s.Path}
+	if strings.ContainsAny(s.Path, "*?[") {
+		matches, err := filepath.Glob(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, errors.New("no file matches the pattern")
+		}
+		paths = matches
+	}
+
+	var files []string
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			files = append(files, p)
+			continue
+		}
+
+		err = filepath.Walk(p, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if ext := strings.ToLower(filepath.Ext(path)); !fi.IsDir() && (ext == ".yar" || ext == ".yara") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// compile adds the rule files of the set to the compiler.
+func (s ruleSet) compile(c *lib.Compiler) (int, error) {
+	files, err := s.files()
+	if err != nil {
+		return 0, fmt.Errorf("could not list yara files %s: %s", s.Path, err)
+	}
+
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, fmt.Errorf("could not open yara file %s: %s", path, err)
+		}
+		err = c.AddFile(f, s.Namespace)
+		f.Close()
+		if err != nil {
+			return 0, fmt.Errorf("could not compile yara file %s: %s", path, err)
+		}
+	}
+
+	return len(files), nil
+}
+
+// externals returns the YARA external variables describing a document, which
+// rules can use in their conditions (e.g. `input == "github" and size < 1000`).
+// A nil document gives their default values, which they must be defined with
+// at compile time.
+func externals(d *vautour.Document) map[string]interface{} {
+	vars := map[string]interface{}{
+		"input":    "",
+		"title":    "",
+		"user":     "",
+		"url":      "",
+		"size":     0,
+		"filetype": "",
+		"mime":     "",
+		"language": "",
+	}
+	if d == nil {
+		return vars
+	}
+
+	vars["input"] = d.InputModuleName
+	vars["title"] = d.Title
+	vars["user"] = d.User
+	vars["url"] = d.URL
+	vars["size"] = d.Size
+	if d.Class != nil {
+		vars["filetype"] = d.Class.FileType
+		vars["mime"] = d.Class.MIME
+		vars["language"] = d.Class.Language
+	}
+	return vars
+}
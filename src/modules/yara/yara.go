@@ -22,17 +22,37 @@ import (
 	"github.com/quentin-m/vautour/src/modules"
 	"github.com/quentin-m/vautour/src/pkg/vautour"
 	lib "github.com/hillu/go-yara"
-	"os"
+	"sync"
 	"time"
 	log "github.com/sirupsen/logrus"
 )
 
 type yara struct{
+	// Rules are the sources of rules, each compiled in its own namespace. Path
+	// is compiled in the default namespace when none is configured.
+	Rules []ruleSet
 	Path string
 	Timeout time.Duration
 
+	// MaxStrings is the maximum number of string matches listed per rule.
+	MaxStrings int
+	// MaxDataLength caps the matched data listed, in bytes.
+	MaxDataLength int
+	// RedactData masks the matched data, but its first bytes, in the data &
+	// context listed: they often are secrets, which would otherwise reach the
+	// unprivileged outputs.
+	RedactData bool
+	// ContextLines is the number of lines listed before & after the matches,
+	// up to MaxContextLength bytes around them.
+	ContextLines int
+	MaxContextLength int
+
+	// The external variables are defined on the compiled rules, which
+	// concurrent scans therefore cannot share: each scan takes a copy of the
+	// rules out of the pool, which grows up to the number of processor threads.
 	c *lib.Compiler
-	r *lib.Rules
+	m sync.Mutex
+	pool []*lib.Rules
 }
 
 func init() {
@@ -43,6 +63,11 @@ func (y *yara) Configure(moduleConfig *modules.ModuleConfig) error {
 	// Default configuration.
 	y.Path = "rules/_index.yar"
 	y.Timeout = 15 * time.Second
+	y.MaxStrings = 20
+	y.MaxDataLength = 256
+	y.RedactData = true
+	y.ContextLines = 2
+	y.MaxContextLength = 1024
 
 	// Parse parameters.
 	if err := modules.ParseParams(moduleConfig.Params, &y); err != nil {
@@ -56,16 +81,26 @@ func (y *yara) Configure(moduleConfig *modules.ModuleConfig) error {
 	}
 	y.c = c
 
-	// Read rules file.
-	f, err := os.Open(y.Path)
-	if err != nil {
-		return fmt.Errorf("could not open yara file %s: %s", y.Path, err)
+	// Define the external variables describing the documents.
+	for name, value := range externals(nil) {
+		if err := c.DefineVariable(name, value); err != nil {
+			return fmt.Errorf("could not define yara variable %s: %s", name, err)
+		}
 	}
-	defer f.Close()
 
 	// Compile rules.
-	if err := c.AddFile(f, ""); err != nil {
-		return fmt.Errorf("could not compile yara rules: %s", err)
+	if len(y.Rules) == 0 {
+		y.Rules = []ruleSet{{Path: y.Path}}
+	}
+	for _, s := range y.Rules {
+		if s.Disabled {
+			continue
+		}
+		n, err := s.compile(c)
+		if err != nil {
+			return err
+		}
+		log.WithField("role", "processor").WithField("module", "yara").WithField("namespace", s.Namespace).Debugf("compiled %d yara files from %s", n, s.Path)
 	}
 
 	r, err := c.GetRules()
@@ -73,33 +108,74 @@ func (y *yara) Configure(moduleConfig *modules.ModuleConfig) error {
 		return fmt.Errorf("could not read the compiled rules back: %s", err)
 	}
 	for _, r := range r.GetRules() {
-		log.WithField("role", "processor").WithField("module", "yara").Debugf("compiled rule %s", r.Identifier())
+		log.WithField("role", "processor").WithField("module", "yara").Debugf("compiled rule %s:%s", r.Namespace(), r.Identifier())
 	}
-	y.r = r
+	y.pool = []*lib.Rules{r}
 
 	return nil
 }
 
 func (y *yara) Process(d *vautour.Document) error {
-	matches, err := y.r.ScanMem(d.Content, 0, y.Timeout)
+	r, err := y.acquire()
+	if err != nil {
+		return err
+	}
+	defer y.release(r)
+
+	for name, value := range externals(d) {
+		if err := r.DefineVariable(name, value); err != nil {
+			return fmt.Errorf("could not define yara variable %s: %s", name, err)
+		}
+	}
+	matches, err := r.ScanMem(d.Content, 0, y.Timeout)
 	if err != nil {
 		return err
 	}
 
 	for _, match := range matches {
-		j, err := json.Marshal(match)
+		j, err := json.Marshal(y.newMatch(d.Content, d.Sensitive, match))
 		if err != nil {
 			log.WithField("role", "processor").WithField("module", "yara").WithField("item_id", d.ID).WithField("rule", match.Rule).Warn("failed to marshal match result")
 		}
-		d.Processed = append(d.Processed, vautour.ProcessedData{
+		pd := vautour.ProcessedData{
 			Module: "yara", // TODO: This is not the actual module name, but the driver name - but I am feeling lazy.
 			RawMessage: j,
-		})
-		if score, ok := match.Meta["score"].(int32); ok && int(score) > d.Score {
-			d.Score = int(score)
 		}
+		if score, ok := match.Meta["score"].(int32); ok {
+			pd.Score = int(score)
+		}
+		for _, s := range match.Strings {
+			pd.Spans = append(pd.Spans, vautour.Span{Start: int(s.Offset), End: int(s.Offset) + len(s.Data)})
+		}
+		d.Processed = append(d.Processed, pd)
+		d.AddTags(match.Tags...)
 		log.WithField("role", "processor").WithField("module", "yara").WithField("item_id", d.ID).WithField("rule", match.Rule).Debug("matched rule")
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// acquire takes a copy of the compiled rules out of the pool, or has the
+// compiler create one if they are all in use.
+func (y *yara) acquire() (*lib.Rules, error) {
+	y.m.Lock()
+	defer y.m.Unlock()
+
+	if n := len(y.pool); n > 0 {
+		r := y.pool[n-1]
+		y.pool = y.pool[:n-1]
+		return r, nil
+	}
+	r, err := y.c.GetRules()
+	if err != nil {
+		return nil, fmt.Errorf("could not copy the compiled rules: %s", err)
+	}
+	return r, nil
+}
+
+// release gives a copy of the compiled rules back to the pool.
+func (y *yara) release(r *lib.Rules) {
+	y.m.Lock()
+	y.pool = append(y.pool, r)
+	y.m.Unlock()
+}
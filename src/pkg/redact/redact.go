@@ -0,0 +1,275 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package redact masks sensitive values (secrets, card numbers, national IDs,
+// custom patterns) before documents leave the pipeline, while keeping enough
+// of them for analysts to correlate.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// ModePartial keeps the first & last characters of the values.
+	ModePartial = "partial"
+	// ModeHash replaces the values by a salted hash.
+	ModeHash = "hash"
+)
+
+// Config configures a Redactor.
+type Config struct {
+	// Mode is either ModePartial or ModeHash.
+	Mode string
+	// Prefix & Suffix are the number of leading & trailing characters kept in
+	// partial mode, as long as most of the value remains masked.
+	Prefix int
+	Suffix int
+	// Salt is the key of the hashes, required in hash mode.
+	Salt string
+
+	// Secrets masks the spans that the processors marked sensitive.
+	Secrets bool
+	// Cards masks Luhn-valid payment card numbers.
+	Cards bool
+	// NationalIDs are the national ID formats masked, among us-ssn, uk-nino,
+	// fr-nir & es-dni.
+	NationalIDs []string
+	// Patterns are additional regular expressions, which matches are masked.
+	Patterns []string
+}
+
+// DefaultConfig returns the default configuration, masking everything that
+// can be detected.
+func DefaultConfig() Config {
+	c := Config{Mode: ModePartial, Prefix: 2, Suffix: 4, Secrets: true, Cards: true}
+	for _, id := range nationalIDs {
+		c.NationalIDs = append(c.NationalIDs, id.name)
+	}
+	return c
+}
+
+// UnmarshalYAML sets the defaults of the fields that are not configured.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type config Config
+	cc := config(DefaultConfig())
+	if err := unmarshal(&cc); err != nil {
+		return err
+	}
+	*c = Config(cc)
+	return nil
+}
+
+// detector finds sensitive values, which matches are confirmed by valid when
+// set.
+type detector struct {
+	name  string
+	re    *regexp.Regexp
+	valid func(string) bool
+}
+
+var (
+	cards = detector{"card", regexp.MustCompile(`\b[2-6](?:[ -]?\d){12,18}\b`), func(s string) bool { return luhn(digits(s)) }}
+
+	nationalIDs = []detector{
+		{"us-ssn", regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), validSSN},
+		{"uk-nino", regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`), func(s string) bool {
+			p := strings.ToUpper(s[:2])
+			return p != "BG" && p != "GB" && p != "NK" && p != "KN" && p != "TN" && p != "NT" && p != "ZZ"
+		}},
+		{"fr-nir", regexp.MustCompile(`\b[12] ?\d{2} ?(?:0[1-9]|1[0-2]|[2-9]\d) ?(?:\d{2}|2[ABab]) ?\d{3} ?\d{3} ?\d{2}\b`), validNIR},
+		{"es-dni", regexp.MustCompile(`\b\d{8}-?[A-Z]\b`), validDNI},
+	}
+)
+
+// Redactor masks the sensitive values of contents.
+type Redactor struct {
+	cfg       Config
+	detectors []detector
+}
+
+// New returns a Redactor for the given configuration.
+func New(cfg Config) (*Redactor, error) {
+	switch cfg.Mode {
+	case "", ModePartial:
+		cfg.Mode = ModePartial
+	case ModeHash:
+		if cfg.Salt == "" {
+			return nil, errors.New("a salt is required in hash mode")
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode %q", cfg.Mode)
+	}
+	if cfg.Prefix < 0 || cfg.Suffix < 0 {
+		return nil, errors.New("prefix & suffix must not be negative")
+	}
+
+	r := &Redactor{cfg: cfg}
+	if cfg.Cards {
+		r.detectors = append(r.detectors, cards)
+	}
+	for _, name := range cfg.NationalIDs {
+		found := false
+		for _, id := range nationalIDs {
+			if id.name == name {
+				r.detectors = append(r.detectors, id)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown national ID format %q", name)
+		}
+	}
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %s", p, err)
+		}
+		r.detectors = append(r.detectors, detector{name: "pattern", re: re})
+	}
+	return r, nil
+}
+
+// Redact returns a copy of b, with the detected values & the given spans
+// (offsets of sensitive values in b) masked.
+func (r *Redactor) Redact(b []byte, spans [][2]int) []byte {
+	var ranges [][2]int
+	if r.cfg.Secrets {
+		for _, s := range spans {
+			if s[0] >= 0 && s[0] < s[1] && s[1] <= len(b) {
+				ranges = append(ranges, s)
+			}
+		}
+	}
+	for _, d := range r.detectors {
+		for _, m := range d.re.FindAllIndex(b, -1) {
+			if d.valid == nil || d.valid(string(b[m[0]:m[1]])) {
+				ranges = append(ranges, [2]int{m[0], m[1]})
+			}
+		}
+	}
+	if len(ranges) == 0 {
+		return append([]byte(nil), b...)
+	}
+
+	// Merge the overlapping ranges, and mask them.
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	out := make([]byte, 0, len(b))
+	last := 0
+	for i := 0; i < len(ranges); {
+		start, end := ranges[i][0], ranges[i][1]
+		for i++; i < len(ranges) && ranges[i][0] < end; i++ {
+			if ranges[i][1] > end {
+				end = ranges[i][1]
+			}
+		}
+		if start < last {
+			start = last
+		}
+		out = append(out, b[last:start]...)
+		out = append(out, r.mask(string(b[start:end]))...)
+		last = end
+	}
+	return append(out, b[last:]...)
+}
+
+// RedactString masks the detected values of s.
+func (r *Redactor) RedactString(s string) string {
+	return string(r.Redact([]byte(s), nil))
+}
+
+func (r *Redactor) mask(v string) string {
+	if r.cfg.Mode == ModeHash {
+		h := hmac.New(sha256.New, []byte(r.cfg.Salt))
+		h.Write([]byte(v))
+		return "[redacted:" + hex.EncodeToString(h.Sum(nil))[:16] + "]"
+	}
+
+	rs := []rune(v)
+	keep := r.cfg.Prefix + r.cfg.Suffix
+	if keep == 0 || 2*keep > len(rs) {
+		return strings.Repeat("*", len(rs))
+	}
+	return string(rs[:r.cfg.Prefix]) + strings.Repeat("*", len(rs)-keep) + string(rs[len(rs)-r.cfg.Suffix:])
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// luhn returns whether the digits pass the Luhn checksum.
+func luhn(s string) bool {
+	sum := 0
+	for i := 0; i < len(s); i++ {
+		n := int(s[len(s)-1-i] - '0')
+		if i%2 == 1 {
+			if n *= 2; n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+	}
+	return sum%10 == 0
+}
+
+// validSSN rejects the US social security numbers that are never issued.
+func validSSN(s string) bool {
+	area, group, serial := s[:3], s[4:6], s[7:]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// validNIR verifies the key of French social security numbers, where the
+// Corsican departments 2A & 2B count as 19 & 18.
+func validNIR(s string) bool {
+	s = strings.ToUpper(strings.Replace(s, " ", "", -1))
+	number, key := s[:13], s[13:]
+	number = strings.Replace(strings.Replace(number, "2A", "19", 1), "2B", "18", 1)
+
+	var n int64
+	for _, c := range number {
+		if c < '0' || c > '9' {
+			return false
+		}
+		n = n*10 + int64(c-'0')
+	}
+	var k int64
+	for _, c := range key {
+		k = k*10 + int64(c-'0')
+	}
+	return 97-n%97 == k
+}
+
+// validDNI verifies the control letter of Spanish national identity numbers.
+func validDNI(s string) bool {
+	s = strings.Replace(s, "-", "", 1)
+	var n int
+	for _, c := range s[:8] {
+		n = n*10 + int(c-'0')
+	}
+	return "TRWAGMYFPDXBNJZSQVHLCKE"[n%23] == s[8]
+}
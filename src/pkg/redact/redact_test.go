@@ -0,0 +1,186 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestDetectors(t *testing.T) {
+	for _, tc := range []struct {
+		detector string
+		value    string
+		masked   bool
+	}{
+		{"card", "4111 1111 1111 1111", true},
+		{"card", "5500-0000-0000-0004", true},
+		{"card", "378282246310005", true},
+		{"card", "4111 1111 1111 1112", false},
+		{"card", "4111111111111110", false},
+
+		{"us-ssn", "123-45-6789", true},
+		{"us-ssn", "000-12-3456", false},
+		{"us-ssn", "666-12-3456", false},
+		{"us-ssn", "900-12-3456", false},
+		{"us-ssn", "123-00-4567", false},
+		{"us-ssn", "123-45-0000", false},
+
+		{"uk-nino", "AB123456C", true},
+		{"uk-nino", "AB 12 34 56 C", true},
+		{"uk-nino", "BG123456C", false},
+		{"uk-nino", "ZZ123456C", false},
+		{"uk-nino", "QQ123456C", false},
+		{"uk-nino", "AB123456E", false},
+
+		{"fr-nir", "184127645108946", true},
+		{"fr-nir", "1 84 12 76 451 089 46", true},
+		{"fr-nir", "184127645108947", false},
+		{"fr-nir", "269052A12345688", true},
+		{"fr-nir", "269052B12345618", true},
+		{"fr-nir", "269052A12345618", false},
+
+		{"es-dni", "12345678Z", true},
+		{"es-dni", "12345678-Z", true},
+		{"es-dni", "12345678A", false},
+	} {
+		cfg := Config{Mode: ModePartial}
+		if tc.detector == "card" {
+			cfg.Cards = true
+		} else {
+			cfg.NationalIDs = []string{tc.detector}
+		}
+		r, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := "id: " + tc.value + "\n"
+		if tc.masked {
+			want = "id: " + strings.Repeat("*", len(tc.value)) + "\n"
+		}
+		if got := r.RedactString("id: " + tc.value + "\n"); got != want {
+			t.Errorf("%s %q: expected %q, got %q", tc.detector, tc.value, want, got)
+		}
+	}
+}
+
+func TestRedactSpans(t *testing.T) {
+	r, err := New(Config{Mode: ModePartial, Secrets: true, Patterns: []string{`token=\w+`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := []byte("user=admin password=hunter2 token=abcdef end")
+
+	for _, tc := range []struct {
+		name  string
+		spans [][2]int
+		want  string
+	}{
+		{"none", nil, "user=admin password=hunter2 ************ end"},
+		{"single", [][2]int{{20, 27}}, "user=admin password=******* ************ end"},
+		{"overlapping", [][2]int{{5, 15}, {11, 27}}, "user=********************** ************ end"},
+		{"nested", [][2]int{{5, 27}, {11, 20}}, "user=********************** ************ end"},
+		{"adjacent", [][2]int{{5, 10}, {10, 20}}, "user=***************hunter2 ************ end"},
+		{"over a pattern", [][2]int{{20, 31}}, "user=admin password=******************** end"},
+		{"out of range", [][2]int{{-1, 3}, {40, 100}, {8, 4}}, "user=admin password=hunter2 ************ end"},
+	} {
+		if got := string(r.Redact(b, tc.spans)); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+	if string(b) != "user=admin password=hunter2 token=abcdef end" {
+		t.Errorf("the content was modified: %q", b)
+	}
+
+	// Spans are ignored unless secrets are masked.
+	r, _ = New(Config{Mode: ModePartial})
+	if got := string(r.Redact(b, [][2]int{{20, 27}})); got != string(b) {
+		t.Errorf("unexpected %q", got)
+	}
+}
+
+func TestMask(t *testing.T) {
+	partial, _ := New(Config{Mode: ModePartial, Prefix: 2, Suffix: 4})
+	for v, want := range map[string]string{
+		"4111111111111111": "41**********1111",
+		"hunter2hunter2":   "hu********ter2",
+		"short":            "*****",
+		"héllo wörld!":     "hé******rld!",
+	} {
+		if got := partial.mask(v); got != want {
+			t.Errorf("expected %q for %q, got %q", want, v, got)
+		}
+	}
+
+	hash, _ := New(Config{Mode: ModeHash, Salt: "salt"})
+	other, _ := New(Config{Mode: ModeHash, Salt: "pepper"})
+	h := hash.mask("hunter2")
+	if !strings.HasPrefix(h, "[redacted:") || len(h) != len("[redacted:]")+16 || strings.Contains(h, "hunter2") {
+		t.Errorf("unexpected hash %q", h)
+	}
+	if hash.mask("hunter2") != h || hash.mask("hunter3") == h || other.mask("hunter2") == h {
+		t.Error("hashes must be stable for a salt, and differ across values & salts")
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		cfg   Config
+		valid bool
+	}{
+		{"default", DefaultConfig(), true},
+		{"empty", Config{}, true},
+		{"unknown mode", Config{Mode: "erase"}, false},
+		{"hash without salt", Config{Mode: ModeHash}, false},
+		{"hash", Config{Mode: ModeHash, Salt: "salt"}, true},
+		{"negative prefix", Config{Prefix: -1}, false},
+		{"unknown national ID", Config{NationalIDs: []string{"de-id"}}, false},
+		{"invalid pattern", Config{Patterns: []string{"("}}, false},
+	} {
+		_, err := New(tc.cfg)
+		if tc.valid && err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
+
+func TestUnmarshalYAML(t *testing.T) {
+	var c Config
+	if err := yaml.Unmarshal([]byte("mode: hash\nsalt: s3cr3t\nnationalids: [fr-nir]\n"), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Mode != ModeHash || c.Salt != "s3cr3t" || c.Prefix != 2 || c.Suffix != 4 || !c.Secrets || !c.Cards {
+		t.Errorf("unexpected configuration %+v", c)
+	}
+	if len(c.NationalIDs) != 1 || c.NationalIDs[0] != "fr-nir" {
+		t.Errorf("unexpected national IDs %v", c.NationalIDs)
+	}
+
+	if err := yaml.Unmarshal([]byte("cards: false\n"), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Mode != ModePartial || c.Cards || len(c.NationalIDs) != len(nationalIDs) {
+		t.Errorf("unexpected configuration %+v", c)
+	}
+}
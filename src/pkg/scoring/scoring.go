@@ -0,0 +1,253 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package scoring combines the score contributions of the processors into the
+// final score of documents, and maps it to a severity level.
+package scoring
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	// StrategyMax keeps the highest contribution.
+	StrategyMax = "max"
+	// StrategySum adds the contributions up.
+	StrategySum = "sum"
+	// StrategyWeighted adds the contributions up, weighted by module.
+	StrategyWeighted = "weighted"
+)
+
+// Severity levels, from the lowest.
+const (
+	Info     = "info"
+	Low      = "low"
+	Medium   = "medium"
+	High     = "high"
+	Critical = "critical"
+)
+
+var levels = []string{Info, Low, Medium, High, Critical}
+
+// Config configures a Scorer.
+type Config struct {
+	// Strategy is StrategyMax, StrategySum or StrategyWeighted. Negative
+	// contributions are penalties, subtracted whatever the strategy.
+	Strategy string
+	// Weights are the weights of the contributions by module, 1 by default,
+	// in weighted strategy.
+	Weights map[string]float64
+	// MaxScore caps the final score, unless 0.
+	MaxScore int
+	// Severities are the minimum scores of the severity levels above info.
+	Severities map[string]int
+	// Rules raise the score or severity of the documents matching them.
+	Rules []Rule
+}
+
+// Rule raises the score or severity of the documents bearing all of its tags,
+// with contributions of all of its modules, and a score of at least MinScore.
+type Rule struct {
+	Name     string
+	Tags     []string
+	Modules  []string
+	MinScore int
+
+	// Score & Severity are the minimum score & severity of the documents
+	// matching the rule.
+	Score    int
+	Severity string
+}
+
+// DefaultConfig returns the default configuration, keeping the highest
+// contribution.
+func DefaultConfig() Config {
+	return Config{
+		Strategy:   StrategyMax,
+		Severities: map[string]int{Low: 10, Medium: 30, High: 60, Critical: 90},
+	}
+}
+
+// UnmarshalYAML sets the defaults of the fields that are not configured.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type config Config
+	cc := config(DefaultConfig())
+	if err := unmarshal(&cc); err != nil {
+		return err
+	}
+	*c = Config(cc)
+	return nil
+}
+
+// Contribution is the score contributed by a module.
+type Contribution struct {
+	Module string
+	Score  int
+}
+
+// Scorer computes the final score & severity of documents.
+type Scorer struct {
+	cfg Config
+}
+
+// New returns a Scorer for the given configuration.
+func New(cfg Config) (*Scorer, error) {
+	switch cfg.Strategy {
+	case "":
+		cfg.Strategy = StrategyMax
+	case StrategyMax, StrategySum, StrategyWeighted:
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", cfg.Strategy)
+	}
+	if cfg.MaxScore < 0 {
+		return nil, errors.New("max score must not be negative")
+	}
+	for l := range cfg.Severities {
+		if Level(l) <= 0 {
+			return nil, fmt.Errorf("unknown severity level %q", l)
+		}
+	}
+	// Compare every configured level to the ones above, which may be unset.
+	for i := 1; i < len(levels); i++ {
+		lower, ok := cfg.Severities[levels[i]]
+		if !ok {
+			continue
+		}
+		for _, l := range levels[i+1:] {
+			if upper, ok := cfg.Severities[l]; ok && lower > upper {
+				return nil, fmt.Errorf("severity %s must not require a higher score than %s", levels[i], l)
+			}
+		}
+	}
+	for _, r := range cfg.Rules {
+		if r.Severity != "" && Level(r.Severity) < 0 {
+			return nil, fmt.Errorf("unknown severity level %q in rule %s", r.Severity, r.Name)
+		}
+	}
+	return &Scorer{cfg: cfg}, nil
+}
+
+// Score returns the final score & severity of a document, given its
+// contributions & tags, as well as the names of the rules it matched.
+func (s *Scorer) Score(contributions []Contribution, tags []string) (int, string, []string) {
+	var total, penalty float64
+	for _, c := range contributions {
+		v := float64(c.Score)
+		if c.Score < 0 {
+			penalty += v
+			continue
+		}
+		switch s.cfg.Strategy {
+		case StrategyMax:
+			total = math.Max(total, v)
+		case StrategySum:
+			total += v
+		case StrategyWeighted:
+			w, ok := s.cfg.Weights[c.Module]
+			if !ok {
+				w = 1
+			}
+			total += w * v
+		}
+	}
+	score := s.clamp(int(math.Round(total + penalty)))
+	severity := s.severity(score)
+
+	var matched []string
+	for _, r := range s.cfg.Rules {
+		if !r.matches(contributions, tags, score) {
+			continue
+		}
+		matched = append(matched, r.Name)
+		if r.Score > score {
+			score = s.clamp(r.Score)
+		}
+		if l := s.severity(score); Level(l) > Level(severity) {
+			severity = l
+		}
+		if Level(r.Severity) > Level(severity) {
+			severity = r.Severity
+		}
+	}
+	return score, severity, matched
+}
+
+func (s *Scorer) clamp(score int) int {
+	if score < 0 {
+		return 0
+	}
+	if s.cfg.MaxScore > 0 && score > s.cfg.MaxScore {
+		return s.cfg.MaxScore
+	}
+	return score
+}
+
+// severity returns the highest level which minimum score is reached.
+func (s *Scorer) severity(score int) string {
+	severity := Info
+	for _, l := range levels[1:] {
+		if min, ok := s.cfg.Severities[l]; ok && score >= min {
+			severity = l
+		}
+	}
+	return severity
+}
+
+func (r *Rule) matches(contributions []Contribution, tags []string, score int) bool {
+	if score < r.MinScore {
+		return false
+	}
+	for _, t := range r.Tags {
+		if !contains(tags, t) {
+			return false
+		}
+	}
+	for _, m := range r.Modules {
+		found := false
+		for _, c := range contributions {
+			if c.Module == m {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Level returns the rank of a severity level, from 0 for info to 4 for
+// critical, or -1 if unknown.
+func Level(l string) int {
+	for i, s := range levels {
+		if s == l {
+			return i
+		}
+	}
+	return -1
+}
+
+func contains(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
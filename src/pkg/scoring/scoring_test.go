@@ -0,0 +1,190 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package scoring
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		cfg   Config
+		valid bool
+	}{
+		{"default", DefaultConfig(), true},
+		{"no strategy", Config{}, true},
+		{"unknown strategy", Config{Strategy: "avg"}, false},
+		{"negative max score", Config{MaxScore: -1}, false},
+		{"unknown level", Config{Severities: map[string]int{"severe": 50}}, false},
+		{"info level", Config{Severities: map[string]int{Info: 0}}, false},
+		{"unordered adjacent levels", Config{Severities: map[string]int{Low: 50, Medium: 10}}, false},
+		{"unordered distant levels", Config{Severities: map[string]int{Low: 50, High: 10}}, false},
+		{"ordered distant levels", Config{Severities: map[string]int{Low: 10, Critical: 90}}, true},
+		{"equal levels", Config{Severities: map[string]int{Medium: 30, High: 30}}, true},
+		{"unknown rule severity", Config{Rules: []Rule{{Name: "r", Severity: "severe"}}}, false},
+	} {
+		_, err := New(tc.cfg)
+		if tc.valid && err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	rule := Rule{Name: "valid-credential", Tags: []string{"credential-valid"}, Modules: []string{"validate"}, MinScore: 10, Score: 70, Severity: Critical}
+
+	for _, tc := range []struct {
+		name          string
+		strategy      string
+		weights       map[string]float64
+		maxScore      int
+		rules         []Rule
+		contributions []Contribution
+		tags          []string
+		score         int
+		severity      string
+		matched       []string
+	}{
+		{
+			name:     "no contributions",
+			strategy: StrategyMax,
+			score:    0,
+			severity: Info,
+		},
+		{
+			name:          "max",
+			strategy:      StrategyMax,
+			contributions: []Contribution{{"yara", 20}, {"secrets", 50}, {"entropy", 3}},
+			score:         50,
+			severity:      Medium,
+		},
+		{
+			name:          "sum",
+			strategy:      StrategySum,
+			contributions: []Contribution{{"yara", 20}, {"secrets", 50}, {"entropy", 3}},
+			score:         73,
+			severity:      High,
+		},
+		{
+			name:          "weighted",
+			strategy:      StrategyWeighted,
+			weights:       map[string]float64{"secrets": 0.5, "entropy": 0},
+			contributions: []Contribution{{"yara", 20}, {"secrets", 50}, {"entropy", 3}},
+			score:         45,
+			severity:      Medium,
+		},
+		{
+			name:          "penalty in max",
+			strategy:      StrategyMax,
+			contributions: []Contribution{{"yara", 60}, {"validate", -65}},
+			score:         0,
+			severity:      Info,
+		},
+		{
+			name:          "penalty in sum",
+			strategy:      StrategySum,
+			contributions: []Contribution{{"yara", 40}, {"secrets", 30}, {"validate", -5}},
+			score:         65,
+			severity:      High,
+		},
+		{
+			name:          "clamp",
+			strategy:      StrategySum,
+			maxScore:      100,
+			contributions: []Contribution{{"yara", 80}, {"secrets", 50}},
+			score:         100,
+			severity:      Critical,
+		},
+		{
+			name:          "rule",
+			strategy:      StrategyMax,
+			rules:         []Rule{rule},
+			contributions: []Contribution{{"validate", 30}},
+			tags:          []string{"credential-valid", "aws"},
+			score:         70,
+			severity:      Critical,
+			matched:       []string{"valid-credential"},
+		},
+		{
+			name:          "rule clamped",
+			strategy:      StrategyMax,
+			maxScore:      50,
+			rules:         []Rule{rule},
+			contributions: []Contribution{{"validate", 30}},
+			tags:          []string{"credential-valid"},
+			score:         50,
+			severity:      Critical,
+			matched:       []string{"valid-credential"},
+		},
+		{
+			name:          "rule missing a tag",
+			strategy:      StrategyMax,
+			rules:         []Rule{rule},
+			contributions: []Contribution{{"validate", 30}},
+			tags:          []string{"aws"},
+			score:         30,
+			severity:      Medium,
+		},
+		{
+			name:          "rule missing a module",
+			strategy:      StrategyMax,
+			rules:         []Rule{rule},
+			contributions: []Contribution{{"yara", 30}},
+			tags:          []string{"credential-valid"},
+			score:         30,
+			severity:      Medium,
+		},
+		{
+			name:          "rule below its minimum score",
+			strategy:      StrategyMax,
+			rules:         []Rule{rule},
+			contributions: []Contribution{{"validate", 30}, {"validate", -25}},
+			tags:          []string{"credential-valid"},
+			score:         5,
+			severity:      Info,
+		},
+	} {
+		cfg := DefaultConfig()
+		cfg.Strategy, cfg.Weights, cfg.MaxScore, cfg.Rules = tc.strategy, tc.weights, tc.maxScore, tc.rules
+		s, err := New(cfg)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+
+		score, severity, matched := s.Score(tc.contributions, tc.tags)
+		if score != tc.score || severity != tc.severity || !reflect.DeepEqual(matched, tc.matched) {
+			t.Errorf("%s: expected %d, %s, %v, got %d, %s, %v", tc.name, tc.score, tc.severity, tc.matched, score, severity, matched)
+		}
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	s, err := New(Config{Severities: map[string]int{Low: 10, High: 60}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for score, severity := range map[int]string{0: Info, 9: Info, 10: Low, 59: Low, 60: High, 100: High} {
+		if l := s.severity(score); l != severity {
+			t.Errorf("expected %s for %d, got %s", severity, score, l)
+		}
+	}
+}
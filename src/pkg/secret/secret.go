@@ -0,0 +1,54 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package secret holds helpers shared by the processors looking for secrets.
+package secret
+
+import (
+	"math"
+	"strings"
+)
+
+// Entropy returns the Shannon entropy of s, in bits per character.
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+
+	var e float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		e -= p * math.Log2(p)
+	}
+	return e
+}
+
+// Redact masks a secret, keeping the first characters of long enough ones so
+// that findings can be told apart without being usable.
+func Redact(s string) string {
+	rs := []rune(s)
+	if len(rs) < 12 {
+		return strings.Repeat("*", len(rs))
+	}
+	return string(rs[:4]) + strings.Repeat("*", len(rs)-4)
+}
@@ -0,0 +1,95 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package state persists the progress of inputs (e.g. cursors) across
+// restarts, as a JSON file.
+package state
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// State is a set of key/value pairs, saved to a file on every update. If no
+// path is given, the state is only kept in memory.
+type State struct {
+	path string
+	m    sync.Mutex
+	kv   map[string]string
+}
+
+// Open loads the state saved at the given path, if any.
+func Open(path string) (*State, error) {
+	s := &State{path: path, kv: make(map[string]string)}
+	if path == "" {
+		return s, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.kv); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the value stored under the given key.
+func (s *State) Get(key string) (string, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	v, ok := s.kv[key]
+	return v, ok
+}
+
+// Set stores the value under the given key, and saves the state.
+func (s *State) Set(key, value string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.kv[key] = value
+	if s.path == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(s.kv, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write atomically, so that a crash never leaves a truncated file behind.
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
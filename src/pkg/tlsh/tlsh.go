@@ -0,0 +1,257 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package tlsh implements the TLSH locality sensitive hash (128 buckets,
+// 1-byte checksum), whose digests are compatible with the reference
+// implementation: similar contents get digests at a small distance.
+// https://github.com/trendmicro/tlsh
+package tlsh
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// MinLength is the minimum length of the data to hash.
+	MinLength = 50
+
+	buckets  = 128
+	codeSize = buckets / 4
+	window   = 5
+	prefix   = "T1"
+)
+
+var (
+	// ErrTooShort is returned when hashing less than MinLength bytes.
+	ErrTooShort = errors.New("data is too short")
+	// ErrNotVariedEnough is returned when hashing data lacking variety (e.g.
+	// a repeated pattern), for which a digest would be meaningless.
+	ErrNotVariedEnough = errors.New("data is not varied enough")
+	// ErrInvalidDigest is returned when parsing a malformed digest.
+	ErrInvalidDigest = errors.New("invalid digest")
+)
+
+// pearson is the permutation of the Pearson hash used to map the triplets of
+// the sliding window to buckets.
+var pearson = [256]byte{
+	1, 87, 49, 12, 176, 178, 102, 166, 121, 193, 6, 84, 249, 230, 44, 163,
+	14, 197, 213, 181, 161, 85, 218, 80, 64, 239, 24, 226, 236, 142, 38, 200,
+	110, 177, 104, 103, 141, 253, 255, 50, 77, 101, 81, 18, 45, 96, 31, 222,
+	25, 107, 190, 70, 86, 237, 240, 34, 72, 242, 20, 214, 244, 227, 149, 235,
+	97, 234, 57, 22, 60, 250, 82, 175, 208, 5, 127, 199, 111, 62, 135, 248,
+	174, 169, 211, 58, 66, 154, 106, 195, 245, 171, 17, 187, 182, 179, 0, 243,
+	132, 56, 148, 75, 128, 133, 158, 100, 130, 126, 91, 13, 153, 246, 216, 219,
+	119, 68, 223, 78, 83, 88, 201, 99, 122, 11, 92, 32, 136, 114, 52, 10,
+	138, 30, 48, 183, 156, 35, 61, 26, 143, 74, 251, 94, 129, 162, 63, 152,
+	170, 7, 115, 167, 241, 206, 3, 150, 55, 59, 151, 220, 90, 53, 23, 131,
+	125, 173, 15, 238, 79, 95, 89, 16, 105, 137, 225, 224, 217, 160, 37, 123,
+	118, 73, 2, 157, 46, 116, 9, 145, 134, 228, 207, 212, 202, 215, 69, 229,
+	27, 188, 67, 124, 168, 252, 42, 4, 29, 108, 21, 247, 19, 205, 39, 203,
+	233, 40, 186, 147, 198, 192, 155, 33, 164, 191, 98, 204, 165, 180, 117, 76,
+	140, 36, 210, 172, 41, 54, 159, 8, 185, 232, 113, 196, 231, 47, 146, 120,
+	51, 65, 28, 144, 254, 221, 93, 189, 194, 139, 112, 43, 71, 109, 184, 209,
+}
+
+// Digest is a TLSH digest.
+type Digest struct {
+	checksum byte
+	lvalue   byte
+	q1ratio  byte
+	q2ratio  byte
+	// code holds the quartile (2 bits) of every bucket, in the order of the
+	// string representation.
+	code [codeSize]byte
+}
+
+// Hash returns the digest of the data.
+func Hash(data []byte) (*Digest, error) {
+	if len(data) < MinLength {
+		return nil, ErrTooShort
+	}
+
+	var counts [256]uint32
+	var checksum byte
+	for i := window - 1; i < len(data); i++ {
+		c0, c1, c2, c3, c4 := data[i], data[i-1], data[i-2], data[i-3], data[i-4]
+
+		checksum = mapping(0, c0, c1, checksum)
+		counts[mapping(2, c0, c1, c2)]++
+		counts[mapping(3, c0, c1, c3)]++
+		counts[mapping(5, c0, c2, c3)]++
+		counts[mapping(7, c0, c2, c4)]++
+		counts[mapping(11, c0, c1, c4)]++
+		counts[mapping(13, c0, c3, c4)]++
+	}
+
+	q1, q2, q3 := quartiles(counts[:buckets])
+	nonzero := 0
+	for _, c := range counts[:buckets] {
+		if c > 0 {
+			nonzero++
+		}
+	}
+	if q3 == 0 || nonzero <= buckets/2 {
+		return nil, ErrNotVariedEnough
+	}
+
+	d := &Digest{
+		checksum: checksum,
+		lvalue:   lvalue(len(data)),
+		q1ratio:  byte(uint32(float32(q1*100)/float32(q3)) % 16),
+		q2ratio:  byte(uint32(float32(q2*100)/float32(q3)) % 16),
+	}
+	for i := 0; i < codeSize; i++ {
+		var h byte
+		for j := 0; j < 4; j++ {
+			switch c := counts[4*i+j]; {
+			case c > q3:
+				h |= 3 << uint(2*j)
+			case c > q2:
+				h |= 2 << uint(2*j)
+			case c > q1:
+				h |= 1 << uint(2*j)
+			}
+		}
+		d.code[codeSize-1-i] = h
+	}
+	return d, nil
+}
+
+// Parse parses the string representation of a digest, with or without its
+// version prefix.
+func Parse(s string) (*Digest, error) {
+	s = strings.TrimPrefix(strings.ToUpper(s), prefix)
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 3+codeSize {
+		return nil, ErrInvalidDigest
+	}
+
+	d := &Digest{
+		checksum: swap(b[0]),
+		lvalue:   swap(b[1]),
+		q1ratio:  b[2] >> 4,
+		q2ratio:  b[2] & 0x0f,
+	}
+	copy(d.code[:], b[3:])
+	return d, nil
+}
+
+// String returns the representation of the digest, as the reference
+// implementation prints it.
+func (d *Digest) String() string {
+	b := make([]byte, 0, 3+codeSize)
+	b = append(b, swap(d.checksum), swap(d.lvalue), d.q1ratio<<4|d.q2ratio)
+	b = append(b, d.code[:]...)
+	return prefix + strings.ToUpper(hex.EncodeToString(b))
+}
+
+// Body returns the hexadecimal representation of the bucket quartiles, which
+// similar contents largely share.
+func (d *Digest) Body() string {
+	return strings.ToUpper(hex.EncodeToString(d.code[:]))
+}
+
+// Distance returns the distance between two digests, including the
+// difference of the lengths of the data: 0 for identical data, and usually
+// less than 100 for similar data.
+func (d *Digest) Distance(o *Digest) int {
+	var diff int
+
+	switch l := modDiff(d.lvalue, o.lvalue, 256); {
+	case l == 1:
+		diff++
+	case l > 1:
+		diff += l * 12
+	}
+	for _, q := range [][2]byte{{d.q1ratio, o.q1ratio}, {d.q2ratio, o.q2ratio}} {
+		if r := modDiff(q[0], q[1], 16); r <= 1 {
+			diff += r
+		} else {
+			diff += (r - 1) * 12
+		}
+	}
+	if d.checksum != o.checksum {
+		diff++
+	}
+
+	for i := range d.code {
+		x, y := d.code[i], o.code[i]
+		for j := uint(0); j < 8; j += 2 {
+			a, b := int(x>>j&3), int(y>>j&3)
+			switch delta := abs(a - b); delta {
+			case 3:
+				diff += 6
+			default:
+				diff += delta
+			}
+		}
+	}
+	return diff
+}
+
+func mapping(salt, i, j, k byte) byte {
+	h := pearson[salt]
+	h = pearson[h^i]
+	h = pearson[h^j]
+	return pearson[h^k]
+}
+
+func quartiles(counts []uint32) (q1, q2, q3 uint32) {
+	sorted := make([]uint32, len(counts))
+	copy(sorted, counts)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	n := len(sorted) / 4
+	return sorted[n-1], sorted[2*n-1], sorted[3*n-1]
+}
+
+// lvalue encodes the length of the data on a logarithmic scale.
+func lvalue(length int) byte {
+	l := float64(length)
+	var i int
+	switch {
+	case length <= 656:
+		i = int(math.Floor(math.Log(l) / math.Log(1.5)))
+	case length <= 3199:
+		i = int(math.Floor(math.Log(l)/math.Log(1.3) - 8.72777))
+	default:
+		i = int(math.Floor(math.Log(l)/math.Log(1.1) - 62.5472))
+	}
+	return byte(i & 0xff)
+}
+
+func modDiff(x, y byte, r int) int {
+	dl := abs(int(x) - int(y))
+	if dr := r - dl; dr < dl {
+		return dr
+	}
+	return dl
+}
+
+func swap(b byte) byte {
+	return b<<4 | b>>4
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
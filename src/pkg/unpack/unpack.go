@@ -0,0 +1,265 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package unpack extracts the files held in compressed streams, archives and
+// multi-part messages, within a size budget.
+package unpack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+)
+
+// Formats.
+const (
+	Gzip  = "gzip"
+	Zlib  = "zlib"
+	Bzip2 = "bzip2"
+	Zip   = "zip"
+	Tar   = "tar"
+	MIME  = "mime"
+)
+
+// ErrBudgetExceeded is returned when the extracted files exceed the budget.
+var ErrBudgetExceeded = errors.New("unpacking budget exceeded")
+
+// File is a file extracted out of a container.
+type File struct {
+	// Name is the path of the file in an archive, or the name of a part. It
+	// is empty for the content of compressed streams, unless recorded in
+	// their header.
+	Name    string
+	Content []byte
+}
+
+// Budget bounds the total size of the extracted files, to protect against
+// decompression bombs.
+type Budget struct {
+	remaining int64
+}
+
+func NewBudget(size int64) *Budget {
+	return &Budget{remaining: size}
+}
+
+// Take consumes n bytes of the budget, if that many remain.
+func (b *Budget) Take(n int) bool {
+	if int64(n) > b.remaining {
+		return false
+	}
+	b.remaining -= int64(n)
+	return true
+}
+
+// Read reads r until EOF, failing if it exceeds the remaining budget.
+func (b *Budget) Read(r io.Reader) ([]byte, error) {
+	c, err := ioutil.ReadAll(io.LimitReader(r, b.remaining+1))
+	if err != nil {
+		return nil, err
+	}
+	if !b.Take(len(c)) {
+		return nil, ErrBudgetExceeded
+	}
+	return c, nil
+}
+
+// Detect returns the format of the content, or an empty string if it is not
+// a supported container.
+func Detect(content []byte) string {
+	switch {
+	case bytes.HasPrefix(content, []byte{0x1f, 0x8b}):
+		return Gzip
+	case len(content) > 2 && content[0] == 0x78 && (content[1] == 0x01 || content[1] == 0x5e || content[1] == 0x9c || content[1] == 0xda):
+		return Zlib
+	case bytes.HasPrefix(content, []byte("BZh")) && len(content) > 3 && content[3] >= '1' && content[3] <= '9':
+		return Bzip2
+	case bytes.HasPrefix(content, []byte("PK\x03\x04")):
+		return Zip
+	case len(content) > 262 && bytes.Equal(content[257:262], []byte("ustar")):
+		return Tar
+	case isMultipart(content):
+		return MIME
+	}
+	return ""
+}
+
+// IsStream returns whether the format is a compressed stream, which holds a
+// single file, rather than an archive.
+func IsStream(format string) bool {
+	return format == Gzip || format == Zlib || format == Bzip2
+}
+
+// Unpack extracts the files held in the content, as per its format. The files
+// extracted before an error are returned along with it.
+func Unpack(content []byte, b *Budget) (string, []File, error) {
+	format := Detect(content)
+
+	var files []File
+	var err error
+	switch format {
+	case Gzip:
+		var r *gzip.Reader
+		if r, err = gzip.NewReader(bytes.NewReader(content)); err == nil {
+			files, err = readStream(r.Name, r, b)
+		}
+	case Zlib:
+		var r io.ReadCloser
+		if r, err = zlib.NewReader(bytes.NewReader(content)); err == nil {
+			files, err = readStream("", r, b)
+		}
+	case Bzip2:
+		files, err = readStream("", ioutil.NopCloser(bzip2.NewReader(bytes.NewReader(content))), b)
+	case Zip:
+		files, err = unzip(content, b)
+	case Tar:
+		files, err = untar(content, b)
+	case MIME:
+		files, err = parts(content, b)
+	default:
+		return "", nil, errors.New("unsupported format")
+	}
+	return format, files, err
+}
+
+func readStream(name string, r io.ReadCloser, b *Budget) ([]File, error) {
+	defer r.Close()
+
+	c, err := b.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	return []File{{Name: name, Content: c}}, nil
+}
+
+func unzip(content []byte, b *Budget) ([]File, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			// e.g. encrypted entries.
+			continue
+		}
+		c, err := b.Read(r)
+		r.Close()
+		if err != nil {
+			return files, err
+		}
+		files = append(files, File{Name: f.Name, Content: c})
+	}
+	return files, nil
+}
+
+func untar(content []byte, b *Budget) ([]File, error) {
+	tr := tar.NewReader(bytes.NewReader(content))
+
+	var files []File
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		} else if err != nil {
+			return files, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		c, err := b.Read(tr)
+		if err != nil {
+			return files, err
+		}
+		files = append(files, File{Name: h.Name, Content: c})
+	}
+}
+
+// isMultipart returns whether the content is a MIME message with multiple
+// parts, e.g. an e-mail with attachments.
+func isMultipart(content []byte) bool {
+	if !bytes.Contains(content[:min(len(content), 4096)], []byte("MIME-Version:")) {
+		return false
+	}
+	m, err := mail.ReadMessage(bytes.NewReader(content))
+	if err != nil {
+		return false
+	}
+	mt, _, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	return err == nil && strings.HasPrefix(mt, "multipart/")
+}
+
+func parts(content []byte, b *Budget) ([]File, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	_, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for i := 1; ; i++ {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return files, nil
+		} else if err != nil {
+			return files, err
+		}
+
+		// Quoted-printable is decoded by the reader already.
+		var r io.Reader = p
+		if strings.EqualFold(p.Header.Get("Content-Transfer-Encoding"), "base64") {
+			r = base64.NewDecoder(base64.StdEncoding, p)
+		}
+		c, err := b.Read(r)
+		if err != nil {
+			return files, err
+		}
+
+		name := p.FileName()
+		if name == "" {
+			name = fmt.Sprintf("part-%d", i)
+		}
+		files = append(files, File{Name: name, Content: c})
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -17,9 +17,9 @@
 package vautour
 
 import (
-	"errors"
 	"fmt"
 	"github.com/coreos/pkg/timeutil"
+	"github.com/pkg/errors"
 	"github.com/quentin-m/vautour/src/modules"
 	"github.com/quentin-m/vautour/src/pkg/stopper"
 	log "github.com/sirupsen/logrus"
@@ -51,6 +51,11 @@ var (
 
 	// Queues.
 	queues = []string{queueDocumentsListed, queueDocumentsScraped, queueDocumentsParsed}
+
+	// Configured modules, by name.
+	instances = make(map[string]interface{})
+	// Configured queue module.
+	queue QueueModule
 )
 
 func Boot(cfg Config) {
@@ -66,10 +71,13 @@ func Boot(cfg Config) {
 	for modS, modC := range cfg.Modules {
 		log.WithField("module", modS).Debug("configuring module")
 
-		mod, err := mod(cfg, modS)
-		if err != nil {
-			log.WithField("module", modS).WithError(err).Fatal("failed to configure module")
+		mod := modules.New(modC.Driver)
+		if mod == nil {
+			log.WithField("module", modS).WithField("driver", modC.Driver).Fatal("undefined module driver")
 		}
+		instances[modS] = mod
+
+		var err error
 		if modT, ok := mod.(InputModule); ok {
 			err = modT.Configure(modC)
 		} else if modT, ok := mod.(ProcessorModule); ok {
@@ -91,6 +99,17 @@ func Boot(cfg Config) {
 	if err != nil {
 		log.Fatalf("failed to find queue module: %s", err)
 	}
+	queue = qModT
+
+	// Configure the scoring of the processed documents.
+	if err := configureScorer(cfg.Processors); err != nil {
+		log.Fatalf("failed to configure scoring: %s", err)
+	}
+
+	// Configure the redaction of the unprivileged outputs.
+	if err := configureRedactors(cfg.Outputs); err != nil {
+		log.Fatalf("failed to configure redaction: %s", err)
+	}
 
 	// Run listers.
 	for _, iModS := range cfg.Inputs.Modules {
@@ -107,7 +126,7 @@ func Boot(cfg Config) {
 	// Run processors.
 	for i := 0; i < cfg.Processors.Threads; i++ {
 		st.Begin()
-		go do(st, qModT, queueDocumentsScraped, queueDocumentsParsed, log.WithField("role", "processor"), func(d *Document) error {return process(cfg, d)})
+		go do(st, qModT, queueDocumentsScraped, queueDocumentsParsed, log.WithField("role", "processor"), func(d *Document) error {return process(cfg, qModT, d)})
 	}
 
 	// Run outputters.
@@ -191,15 +210,19 @@ func scrape(cfg Config, d *Document) error {
 
 	// Scrape
 	if err := sModT.Scrape(d); err != nil {
-		log.WithField("role", "scraper").WithField("module", d.InputModuleName).WithField("item_id", d.ID).WithError(err).Error("scraping failed")
-		return errors.New("scraping failed")
+		if errors.Cause(err) == ErrNotFound {
+			log.WithField("role", "scraper").WithField("module", d.InputModuleName).WithField("item_id", d.ID).WithError(err).Info("document not found, dropping")
+		} else {
+			log.WithField("role", "scraper").WithField("module", d.InputModuleName).WithField("item_id", d.ID).WithError(err).Error("scraping failed")
+		}
+		return errors.Wrap(err, "scraping failed")
 	}
 	log.WithField("role", "scraper").WithField("module", d.InputModuleName).WithField("item_id", d.ID).Debug("scraped document")
 
 	return nil
 }
 
-func process(cfg Config, d *Document) error {
+func process(cfg Config, q QueueModule, d *Document) error {
 	for _, pModN := range cfg.Processors.Modules {
 		// Get the module.
 		pModT, err := processorMod(cfg, pModN)
@@ -215,6 +238,19 @@ func process(cfg Config, d *Document) error {
 		}
 		log.WithField("role", "processor").WithField("module", pModN).WithField("item_id", d.ID).Debug("processed document")
 	}
+	d.mergeSensitive()
+	d.score()
+
+	// Queue the child documents for processing, they are cached so that they
+	// are not queued twice if the parent gets processed again.
+	for _, c := range d.children {
+		if err := q.AddDocument(queueDocumentsScraped, c, listedCacheDuration); err != nil && err != ErrAlreadyExists {
+			log.WithField("role", "processor").WithField("item_id", d.ID).WithField("child_id", c.ID).WithError(err).Warn("failed to add child document to queue")
+			return errors.New("processing failed")
+		}
+		log.WithField("role", "processor").WithField("item_id", d.ID).WithField("child_id", c.ID).Debug("queued child document")
+	}
+	d.children = nil
 
 	return nil
 }
@@ -228,8 +264,12 @@ func output(cfg Config, d *Document) error {
 			return err
 		}
 
-		// Send the item.
-		if err := oModT.Send(d); err != nil {
+		// Send the item, redacted unless the output is privileged.
+		od := d
+		if r, ok := redactors[oModN]; ok {
+			od = d.redacted(r)
+		}
+		if err := oModT.Send(od); err != nil {
 			log.WithField("role", "output").WithField("module", oModN).WithField("item_id", d.ID).WithError(err).Error("output failed")
 			return errors.New("processing failed")
 		}
@@ -262,9 +302,13 @@ func do(st *stopper.Stopper, q QueueModule, srcQueue, dstQueue string, logger *l
 			}
 			dO, _ := NewDocumentFromJSON(j)
 
-			// Run function
+			// Run function, documents that do not exist anymore are dropped rather than retried.
+			var drop bool
 			if err := f(d); err != nil {
-				return
+				if errors.Cause(err) != ErrNotFound {
+					return
+				}
+				drop = true
 			}
 
 			// From that point, prevent the early termination of the routine until we're done.
@@ -272,7 +316,7 @@ func do(st *stopper.Stopper, q QueueModule, srcQueue, dstQueue string, logger *l
 			defer dm.Unlock()
 
 			// Move document in the queues.
-			if dstQueue != "" {
+			if dstQueue != "" && !drop {
 				if err := q.AddDocument(dstQueue, d, 0); err != nil {
 					logger.WithField("item_id", d.ID).WithError(err).Warn("failed to add document to queue")
 					return
@@ -284,6 +328,9 @@ func do(st *stopper.Stopper, q QueueModule, srcQueue, dstQueue string, logger *l
 				logger.WithField("item_id", d.ID).WithError(err).Warn("failed to release document")
 				return
 			}
+			if drop {
+				logger.WithField("item_id", d.ID).Debug("dropped document")
+			}
 		}()
 
 		// Refresh task lock until done.
@@ -323,17 +370,37 @@ func bookkeep(st *stopper.Stopper, q QueueModule) {
 }
 
 func mod(cfg Config, modS string) (interface{}, error) {
-	modC := cfg.Modules[modS]
-	if modC == nil {
+	if cfg.Modules[modS] == nil {
 		return nil, errors.New("module configuration is missing")
 	}
-	mod := modules.Modules[modC.Driver]
+	mod := instances[modS]
 	if mod == nil {
 		return nil, errors.New("undefined module")
 	}
 	return mod, nil
 }
 
+// Processor returns the configured processor module with the given name, so
+// that processors can run others (e.g. on decoded content). It must not be
+// called before Boot has configured the modules, i.e. not from Configure.
+func Processor(pModS string) (ProcessorModule, error) {
+	pModT, ok := instances[pModS].(ProcessorModule)
+	if !ok {
+		return nil, fmt.Errorf("undefined processor module %s", pModS)
+	}
+	return pModT, nil
+}
+
+// Index returns the configured queue module as an index, if it supports it. It
+// must not be called before Boot has configured the modules.
+func Index() (IndexModule, error) {
+	idx, ok := queue.(IndexModule)
+	if !ok {
+		return nil, fmt.Errorf("queue module %T does not support indexes", queue)
+	}
+	return idx, nil
+}
+
 func queueMod(cfg Config, qModS string) (QueueModule, error) {
 	qMod, err := mod(cfg, qModS)
 	if err != nil {
@@ -0,0 +1,131 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package vautour
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/quentin-m/vautour/src/pkg/redact"
+)
+
+// redactors are the redactors of the unprivileged outputs, by name.
+var redactors = make(map[string]*redact.Redactor)
+
+// configureRedactors creates the redactors of the unprivileged outputs.
+func configureRedactors(cfg OutputsConfig) error {
+	privileged := make(map[string]bool)
+	for _, o := range cfg.Privileged {
+		privileged[o] = true
+	}
+
+	for _, o := range cfg.Modules {
+		if privileged[o] {
+			continue
+		}
+
+		rCfg := redact.DefaultConfig()
+		if c, ok := cfg.Redactions[o]; ok && c != nil {
+			rCfg = *c
+		} else if cfg.Redaction != nil {
+			rCfg = *cfg.Redaction
+		}
+
+		r, err := redact.New(rCfg)
+		if err != nil {
+			return fmt.Errorf("invalid redaction of output %s: %s", o, err)
+		}
+		redactors[o] = r
+	}
+	return nil
+}
+
+// redacted returns a copy of the document, with the sensitive values of its
+// content, title, metadata & processed data masked.
+func (d *Document) redacted(r *redact.Redactor) *Document {
+	c := *d
+
+	spans := make([][2]int, 0, len(d.Sensitive))
+	for _, s := range d.Sensitive {
+		spans = append(spans, [2]int{s.Start, s.End})
+	}
+	c.Content = r.Redact(d.Content, spans)
+	// The spans do not match the redacted content anymore.
+	c.Sensitive = nil
+	c.Title = r.RedactString(d.Title)
+
+	if d.Metadata != nil {
+		c.Metadata = redactValue(r, map[string]interface{}(d.Metadata)).(map[string]interface{})
+	}
+
+	c.Processed = make([]ProcessedData, 0, len(d.Processed))
+	for _, p := range d.Processed {
+		c.Processed = append(c.Processed, ProcessedData{Module: p.Module, RawMessage: redactJSON(r, p.RawMessage)})
+	}
+	if d.Processed == nil {
+		c.Processed = nil
+	}
+
+	return &c
+}
+
+// redactJSON masks the strings of a JSON value.
+func redactJSON(r *redact.Redactor, j json.RawMessage) json.RawMessage {
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		// Not JSON, redact it as text.
+		return json.RawMessage(r.Redact(j, nil))
+	}
+	b, err := json.Marshal(redactValue(r, v))
+	if err != nil {
+		return json.RawMessage(r.Redact(j, nil))
+	}
+	return b
+}
+
+// redactValue returns a copy of v, with its strings masked.
+func redactValue(r *redact.Redactor, v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		return r.RedactString(t)
+	case []string:
+		c := make([]string, len(t))
+		for i, s := range t {
+			c[i] = r.RedactString(s)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(t))
+		for i, e := range t {
+			c[i] = redactValue(r, e)
+		}
+		return c
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			c[k] = redactValue(r, e)
+		}
+		return c
+	case Metadata:
+		return Metadata(redactValue(r, map[string]interface{}(t)).(map[string]interface{}))
+	default:
+		return v
+	}
+}
@@ -0,0 +1,57 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package vautour
+
+import (
+	"github.com/quentin-m/vautour/src/pkg/scoring"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// scorer computes the final score & severity of the processed documents.
+var scorer *scoring.Scorer
+
+// configureScorer creates the scorer of the processed documents.
+func configureScorer(cfg ProcessorsConfig) error {
+	sCfg := scoring.DefaultConfig()
+	if cfg.Scoring != nil {
+		sCfg = *cfg.Scoring
+	}
+
+	s, err := scoring.New(sCfg)
+	if err != nil {
+		return err
+	}
+	scorer = s
+	return nil
+}
+
+// score sets the score & severity of the document, out of the contributions
+// of its processed data.
+func (d *Document) score() {
+	if scorer == nil {
+		return
+	}
+
+	contributions := make([]scoring.Contribution, 0, len(d.Processed))
+	for _, p := range d.Processed {
+		contributions = append(contributions, scoring.Contribution{Module: p.Module, Score: p.Score})
+	}
+	var rules []string
+	d.Score, d.Severity, rules = scorer.Score(contributions, d.Tags)
+	log.WithField("role", "processor").WithField("item_id", d.ID).WithField("score", d.Score).WithField("severity", d.Severity).WithField("rules", strings.Join(rules, ",")).Debug("scored document")
+}
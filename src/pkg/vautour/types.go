@@ -17,10 +17,14 @@
 package vautour
 
 import (
+	"bytes"
 	"encoding/json"
 	"github.com/pkg/errors"
 	"github.com/quentin-m/vautour/src/modules"
+	"github.com/quentin-m/vautour/src/pkg/redact"
+	"github.com/quentin-m/vautour/src/pkg/scoring"
 	"github.com/quentin-m/vautour/src/pkg/stopper"
+	"sort"
 	"time"
 )
 
@@ -52,11 +56,21 @@ type ScrapersConfig struct {
 type ProcessorsConfig struct {
 	Modules []string
 	Threads int
+
+	// Scoring combines the scores contributed by the processors, once they
+	// are all done, into the final score & severity of the documents.
+	Scoring *scoring.Config
 }
 
 type OutputsConfig struct {
 	Modules []string
 	Threads int
+
+	// Privileged outputs receive the documents as-is, the others receive them
+	// redacted as configured by Redaction, or by Redactions for that output.
+	Privileged []string
+	Redaction *redact.Config
+	Redactions map[string]*redact.Config
 }
 
 // Document
@@ -72,11 +86,33 @@ type Document struct {
 	CreatedAt time.Time
 	ExpireAt time.Time
 
+	Metadata Metadata `json:",omitempty"`
+	Tags []string `json:",omitempty"`
+	Class *Classification `json:",omitempty"`
+
+	// Documents extracted out of others (e.g. files of an archive) link to
+	// their parent, and are Depth levels below the listed document.
+	ParentID string `json:",omitempty"`
+	ChildIDs []string `json:",omitempty"`
+	Depth int `json:",omitempty"`
+
+	// Near-duplicate documents share a ClusterID, the ID of the first of them,
+	// and list the most similar documents seen before them.
+	ClusterID string `json:",omitempty"`
+	Similar []Similarity `json:",omitempty"`
+
 	Score int
+	// Severity is the level of the score (info, low, medium, high, critical).
+	Severity string `json:",omitempty"`
 	Processed []ProcessedData `json:",omitempty"`
+	// Sensitive are the spans of Content holding sensitive values (e.g.
+	// secrets), which are redacted before reaching unprivileged outputs. They
+	// are sorted & merged once processed.
+	Sensitive []Span `json:",omitempty"`
 
 	// Internally managed //
 	InputModuleName string
+	children []*Document
 }
 
 func NewDocumentFromJSON(s string) (*Document, error) {
@@ -92,10 +128,145 @@ func (d *Document) JSON() string {
 	return string(b)
 }
 
+// AddChild attaches a document extracted out of this one, which the pipeline
+// queues for processing once all the processors are done with the parent.
+func (d *Document) AddChild(c *Document) {
+	c.ParentID = d.ID
+	c.Depth = d.Depth + 1
+	c.InputModuleName = d.InputModuleName
+	d.ChildIDs = append(d.ChildIDs, c.ID)
+	d.children = append(d.children, c)
+}
+
+// Children returns the documents attached with AddChild that are not queued
+// yet.
+func (d *Document) Children() []*Document {
+	return d.children
+}
+
+// MarkSensitive marks Content[start:end] as holding a sensitive value.
+func (d *Document) MarkSensitive(start, end int) {
+	if start < end {
+		d.Sensitive = append(d.Sensitive, Span{Start: start, End: end})
+	}
+}
+
+// mergeSensitive sorts the sensitive spans, and merges the overlapping &
+// adjacent ones (e.g. marked by several processors).
+func (d *Document) mergeSensitive() {
+	if len(d.Sensitive) < 2 {
+		return
+	}
+	sort.Slice(d.Sensitive, func(i, j int) bool { return d.Sensitive[i].Start < d.Sensitive[j].Start })
+
+	merged := d.Sensitive[:1]
+	for _, s := range d.Sensitive[1:] {
+		last := &merged[len(merged)-1]
+		if s.Start > last.End {
+			merged = append(merged, s)
+			continue
+		}
+		if s.End > last.End {
+			last.End = s.End
+		}
+	}
+	d.Sensitive = merged
+}
+
+// SetMetadata sets a metadata value of the document.
+func (d *Document) SetMetadata(key string, value interface{}) {
+	if d.Metadata == nil {
+		d.Metadata = make(Metadata)
+	}
+	d.Metadata[key] = value
+}
+
+// AddTags adds the given tags to the document, unless it bears them already.
+func (d *Document) AddTags(tags ...string) {
+	for _, t := range tags {
+		if t != "" && !d.HasTag(t) {
+			d.Tags = append(d.Tags, t)
+		}
+	}
+}
+
+// HasTag returns whether the document bears the given tag.
+func (d *Document) HasTag(tag string) bool {
+	for _, t := range d.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Metadata
+
+// Metadata holds source-specific data about a document, such as the syntax of
+// a paste or the site of a Stack Exchange post.
+type Metadata map[string]interface{}
+
+// UnmarshalJSON keeps numbers as json.Number, so that a document marshals back
+// to the exact JSON it was read from (which the queues rely on).
+func (m *Metadata) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v map[string]interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Classification
+
+// Classification describes the nature of a document's content, so that the
+// processors & outputs can act depending on it.
+type Classification struct {
+	// FileType is the type of binary contents detected from their magic bytes
+	// (e.g. elf, pe, pdf, zip), or text.
+	FileType string
+	MIME string
+	Binary bool
+	// Language is the detected language or format of text contents (e.g. sql,
+	// env, yaml, json, pem, combolist), if any.
+	Language string `json:",omitempty"`
+	Lines int
+	// Charset is a hint of the encoding of text contents (e.g. ascii, utf-8,
+	// utf-16le, latin1).
+	Charset string `json:",omitempty"`
+}
+
+// Span
+
+// Span is a range of bytes of a document's content.
+type Span struct {
+	Start int
+	End int
+}
+
+// Similarity
+
+// Similarity is the similarity of a document to another, from 0 to 100.
+type Similarity struct {
+	ID string
+	Score int
+}
+
 // Match
 
+// ProcessedData is a result of a processor. Only its RawMessage is serialized:
+// Module, Score & Spans are available to the processors & the scoring stage.
 type ProcessedData struct {
 	Module string
+	// Score is the contribution of the result to the document's score, a
+	// penalty if negative.
+	Score int
+	// Spans are the spans of Content the result is about, if any, which lets
+	// the following processors relate to it (e.g. to validate its findings).
+	Spans []Span
 	json.RawMessage
 }
 
@@ -103,6 +274,19 @@ type ProcessedData struct {
 
 var ErrAlreadyExists = errors.New("document already exists")
 
+// Errors that input modules may return from Scrape, wrapped or not, to tell the
+// pipeline how to handle the failure.
+var (
+	// ErrNotFound indicates that the document does not exist (anymore), and
+	// that it should be dropped rather than retried.
+	ErrNotFound = errors.New("document not found or deleted")
+	// ErrAccessDenied indicates that the source refused to serve the document.
+	ErrAccessDenied = errors.New("access denied")
+	// ErrTransient indicates a temporary failure, after which the document
+	// should be retried.
+	ErrTransient = errors.New("transient failure")
+)
+
 // Interfaces
 
 type QueueModule interface {
@@ -114,6 +298,16 @@ type QueueModule interface {
 	Bookkeep(queues []string)
 }
 
+// IndexModule is implemented by the queue modules that can also store indexes,
+// which processors use to find the documents they saw (e.g. near duplicates).
+type IndexModule interface {
+	// AddToIndex stores the value under every given key of the index, for ttl.
+	AddToIndex(index string, keys []string, value string, ttl time.Duration) error
+	// LookupIndex returns the unexpired values stored under any of the given
+	// keys, at most max per key (0 for all) starting with the latest.
+	LookupIndex(index string, keys []string, max int) ([]string, error)
+}
+
 type InputModule interface {
 	Configure(*modules.ModuleConfig) error
 	List(*stopper.Stopper, chan *Document) error
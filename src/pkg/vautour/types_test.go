@@ -0,0 +1,35 @@
+// Vautour - A distributed & extensible web hunter
+// Copyright (C) 2019 Quentin Machu & Vautour contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package vautour
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeSensitive(t *testing.T) {
+	d := &Document{}
+	for _, s := range [][2]int{{40, 50}, {0, 5}, {10, 20}, {15, 18}, {5, 8}, {40, 50}, {20, 30}, {9, 9}, {60, 70}, {65, 80}} {
+		d.MarkSensitive(s[0], s[1])
+	}
+	d.mergeSensitive()
+
+	expected := []Span{{0, 8}, {10, 30}, {40, 50}, {60, 80}}
+	if !reflect.DeepEqual(d.Sensitive, expected) {
+		t.Errorf("expected %v, got %v", expected, d.Sensitive)
+	}
+}